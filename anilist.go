@@ -2,83 +2,422 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rl404/verniy"
 	"golang.org/x/oauth2"
 )
 
+var animeListFields = []verniy.MediaListGroupField{
+	verniy.MediaListGroupFieldStatus,
+	verniy.MediaListGroupFieldEntries(
+		verniy.MediaListFieldID,
+		verniy.MediaListFieldStatus,
+		verniy.MediaListFieldScore,
+		verniy.MediaListFieldProgress,
+		verniy.MediaListFieldStartedAt,
+		verniy.MediaListFieldCompletedAt,
+		verniy.MediaListFieldUpdatedAt,
+		verniy.MediaListFieldCreatedAt,
+		verniy.MediaListFieldHiddenFromStatusLists,
+		verniy.MediaListFieldNotes,
+		verniy.MediaListFieldMedia(
+			verniy.MediaFieldID,
+			verniy.MediaFieldIDMAL,
+			verniy.MediaFieldTitle(
+				verniy.MediaTitleFieldRomaji,
+				verniy.MediaTitleFieldEnglish,
+				verniy.MediaTitleFieldNative,
+			),
+			verniy.MediaFieldFormat,
+			verniy.MediaFieldStatusV2,
+			verniy.MediaFieldEpisodes,
+			verniy.MediaFieldNextAiringEpisode(verniy.AiringScheduleFieldEpisode),
+			verniy.MediaFieldSeasonYear,
+			verniy.MediaFieldSeason,
+			verniy.MediaFieldGenres,
+			verniy.MediaFieldIsAdult,
+		),
+	),
+}
+
+var mangaListFields = []verniy.MediaListGroupField{
+	verniy.MediaListGroupFieldName,
+	verniy.MediaListGroupFieldStatus,
+	verniy.MediaListGroupFieldEntries(
+		verniy.MediaListFieldID,
+		verniy.MediaListFieldStatus,
+		verniy.MediaListFieldScore,
+		verniy.MediaListFieldProgress,
+		verniy.MediaListFieldProgressVolumes,
+		verniy.MediaListFieldRepeat,
+		verniy.MediaListFieldStartedAt,
+		verniy.MediaListFieldCompletedAt,
+		verniy.MediaListFieldUpdatedAt,
+		verniy.MediaListFieldCreatedAt,
+		verniy.MediaListFieldHiddenFromStatusLists,
+		verniy.MediaListFieldNotes,
+		verniy.MediaListFieldMedia(
+			verniy.MediaFieldID,
+			verniy.MediaFieldIDMAL,
+			verniy.MediaFieldTitle(
+				verniy.MediaTitleFieldRomaji,
+				verniy.MediaTitleFieldEnglish,
+				verniy.MediaTitleFieldNative),
+			verniy.MediaFieldType,
+			verniy.MediaFieldFormat,
+			verniy.MediaFieldStatusV2,
+			verniy.MediaFieldChapters,
+			verniy.MediaFieldVolumes,
+			verniy.MediaFieldGenres,
+			verniy.MediaFieldIsAdult,
+		),
+	),
+}
+
 type AnilistClient struct {
 	c *verniy.Client
 
-	username string
+	username   string
+	batchSize  int
+	listStatus verniy.MediaListStatus // fetch only the list of the status if set
 }
 
-func NewAnilistClient(ctx context.Context, oauth *OAuth, username string) (*AnilistClient, error) {
+func NewAnilistClient(ctx context.Context, oauth *OAuth, username, graphqlURL string, batchSize int, listName string) (*AnilistClient, error) {
 	httpClient := oauth2.NewClient(ctx, oauth.TokenSource())
 	httpClient.Timeout = 10 * time.Minute
 
 	v := verniy.New()
+	v.Host = graphqlURL
 	v.Http = *httpClient
 
-	return &AnilistClient{c: v, username: username}, nil
-}
-
-func (c *AnilistClient) GetUserAnimeList(ctx context.Context) ([]verniy.MediaListGroup, error) {
-	return c.c.GetUserAnimeListWithContext(ctx, c.username,
-		verniy.MediaListGroupFieldStatus,
-		verniy.MediaListGroupFieldEntries(
-			verniy.MediaListFieldID,
-			verniy.MediaListFieldStatus,
-			verniy.MediaListFieldScore,
-			verniy.MediaListFieldProgress,
-			verniy.MediaListFieldStartedAt,
-			verniy.MediaListFieldCompletedAt,
-			verniy.MediaListFieldMedia(
-				verniy.MediaFieldID,
-				verniy.MediaFieldIDMAL,
-				verniy.MediaFieldTitle(
-					verniy.MediaTitleFieldRomaji,
-					verniy.MediaTitleFieldEnglish,
-					verniy.MediaTitleFieldNative,
-				),
-				verniy.MediaFieldStatusV2,
-				verniy.MediaFieldEpisodes,
-				verniy.MediaFieldSeasonYear,
-			),
-		),
-	)
+	return &AnilistClient{
+		c:          v,
+		username:   username,
+		batchSize:  batchSize,
+		listStatus: anilistListStatuses[strings.ToLower(listName)],
+	}, nil
+}
 
+// GetUserAnimeList returns anime list and warnings about entries AniList failed to return.
+func (c *AnilistClient) GetUserAnimeList(ctx context.Context) ([]verniy.MediaListGroup, []string, error) {
+	return c.getMediaListCollection(ctx, verniy.MediaTypeAnime, animeListFields)
 }
 
-func (c *AnilistClient) GetUserMangaList(ctx context.Context) ([]verniy.MediaListGroup, error) {
-	return c.c.GetUserMangaListWithContext(ctx, c.username,
-		verniy.MediaListGroupFieldName,
-		verniy.MediaListGroupFieldStatus,
-		verniy.MediaListGroupFieldEntries(
-			verniy.MediaListFieldID,
-			verniy.MediaListFieldStatus,
-			verniy.MediaListFieldScore,
-			verniy.MediaListFieldProgress,
-			verniy.MediaListFieldProgressVolumes,
-			verniy.MediaListFieldStartedAt,
-			verniy.MediaListFieldCompletedAt,
-			verniy.MediaListFieldMedia(
-				verniy.MediaFieldID,
-				verniy.MediaFieldIDMAL,
-				verniy.MediaFieldTitle(
-					verniy.MediaTitleFieldRomaji,
-					verniy.MediaTitleFieldEnglish,
-					verniy.MediaTitleFieldNative),
-				verniy.MediaFieldType,
-				verniy.MediaFieldFormat,
-				verniy.MediaFieldStatusV2,
-				verniy.MediaFieldChapters,
-				verniy.MediaFieldVolumes,
-			),
-		),
+// GetUserMangaList returns manga list and warnings about entries AniList failed to return.
+func (c *AnilistClient) GetUserMangaList(ctx context.Context) ([]verniy.MediaListGroup, []string, error) {
+	return c.getMediaListCollection(ctx, verniy.MediaTypeManga, mangaListFields)
+}
+
+type viewerResponse struct {
+	Data struct {
+		Viewer *struct {
+			Name string `json:"name"`
+		} `json:"Viewer"`
+	} `json:"data"`
+	anilistErrorResponse
+}
+
+// GetViewerName returns the name of the authenticated user.
+func (c *AnilistClient) GetViewerName(ctx context.Context) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"query": verniy.FieldObject("query", nil, verniy.FieldObject("Viewer", nil, "name")),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	data, code, err := c.c.MakeRequest(ctx, body)
+	if err != nil {
+		return "", newSyncError(err)
+	}
+
+	var resp viewerResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("unexpected status code %d: %w", code, err)
+	}
+	if resp.Data.Viewer == nil {
+		if len(resp.Errors) > 0 {
+			return "", newSyncError(resp.anilistErrorResponse)
+		}
+		return "", fmt.Errorf("unexpected status code %d", code)
+	}
+
+	return resp.Data.Viewer.Name, nil
+}
+
+type userScoreFormatResponse struct {
+	Data struct {
+		User *struct {
+			MediaListOptions *struct {
+				ScoreFormat verniy.ScoreFormat `json:"scoreFormat"`
+			} `json:"mediaListOptions"`
+		} `json:"User"`
+	} `json:"data"`
+	anilistErrorResponse
+}
+
+// GetScoreFormat returns the score format of the user, list scores are returned in it.
+func (c *AnilistClient) GetScoreFormat(ctx context.Context) (verniy.ScoreFormat, error) {
+	body, err := json.Marshal(map[string]any{
+		"query": verniy.FieldObject("query", verniy.QueryParam{
+			"$name": "String",
+		}, verniy.FieldObject("User", verniy.QueryParam{
+			"name": "$name",
+		}, verniy.FieldObject("mediaListOptions", nil, "scoreFormat"))),
+		"variables": map[string]any{
+			"name": c.username,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	data, code, err := c.c.MakeRequest(ctx, body)
+	if err != nil {
+		return "", newSyncError(err)
+	}
+
+	var resp userScoreFormatResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("unexpected status code %d: %w", code, err)
+	}
+	if resp.Data.User == nil || resp.Data.User.MediaListOptions == nil {
+		if len(resp.Errors) > 0 {
+			return "", newSyncError(resp.anilistErrorResponse)
+		}
+		return "", fmt.Errorf("unexpected status code %d", code)
+	}
+
+	return resp.Data.User.MediaListOptions.ScoreFormat, nil
+}
+
+type saveMediaListEntryResponse struct {
+	Data struct {
+		SaveMediaListEntry *struct {
+			ID int `json:"id"`
+		} `json:"SaveMediaListEntry"`
+	} `json:"data"`
+	anilistErrorResponse
+}
+
+// SaveNotes replaces notes of the user list entry of the media, other fields are kept.
+func (c *AnilistClient) SaveNotes(ctx context.Context, mediaID int, notes string) error {
+	body, err := json.Marshal(map[string]any{
+		"query": verniy.FieldObject("mutation", verniy.QueryParam{
+			"$mediaId": "Int",
+			"$notes":   "String",
+		}, verniy.FieldObject("SaveMediaListEntry", verniy.QueryParam{
+			"mediaId": "$mediaId",
+			"notes":   "$notes",
+		}, "id")),
+		"variables": map[string]any{
+			"mediaId": mediaID,
+			"notes":   notes,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	data, code, err := c.c.MakeRequest(ctx, body)
+	if err != nil {
+		return newSyncError(err)
+	}
+
+	var resp saveMediaListEntryResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unexpected status code %d: %w", code, err)
+	}
+	if resp.Data.SaveMediaListEntry == nil {
+		if len(resp.Errors) > 0 {
+			return newSyncError(resp.anilistErrorResponse)
+		}
+		return fmt.Errorf("unexpected status code %d", code)
+	}
+
+	return nil
+}
+
+type anilistErrorResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+	} `json:"errors"`
+}
+
+func (r anilistErrorResponse) Error() string {
+	msgs := make([]string, len(r.Errors))
+	for i, e := range r.Errors {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, " | ")
+}
+
+type mediaListCollectionResponse struct {
+	Data struct {
+		MediaListCollection *verniy.MediaListCollection `json:"MediaListCollection"`
+	} `json:"data"`
+	anilistErrorResponse
+}
+
+// getMediaListCollection fetches user list by pages of batchSize entries or at once if batchSize is not set,
+// paging is slower than a single query, but doesn't hit timeouts on huge lists.
+// If AniList rejects the query as too complex, the fetch is restarted with smaller pages
+// until it succeeds or the pages are minAnilistBatchSize entries.
+//
+// AniList may return errors for some entries alongside the data,
+// they are returned as warnings and the returned entries are used.
+func (c *AnilistClient) getMediaListCollection(
+	ctx context.Context,
+	mediaType verniy.MediaType,
+	fields []verniy.MediaListGroupField,
+) ([]verniy.MediaListGroup, []string, error) {
+	batchSize := c.batchSize
+	for {
+		groups, warnings, err := c.fetchMediaListCollection(ctx, mediaType, fields, batchSize)
+		if err == nil || !isComplexityError(err) {
+			return groups, warnings, err
+		}
+
+		next := reducedBatchSize(batchSize)
+		if next < minAnilistBatchSize {
+			return nil, nil, err
+		}
+		log.Printf("AniList %s list query is too complex, retrying with %d entries per request, use -batch-size %d to skip this", mediaType, next, next)
+		batchSize = next
+	}
+}
+
+const (
+	maxAnilistBatchSize = 500
+	minAnilistBatchSize = 25
+)
+
+func validateBatchSize(n int) error {
+	if n < 0 || n > maxAnilistBatchSize {
+		return fmt.Errorf("-batch-size: %d is out of range, use 1-%d or 0 to fetch the whole list at once", n, maxAnilistBatchSize)
+	}
+	return nil
+}
+
+// reducedBatchSize returns the next smaller batch size after a complexity error,
+// the whole list (0) is followed by the biggest page AniList allows.
+func reducedBatchSize(batchSize int) int {
+	if batchSize <= 0 || batchSize > maxAnilistBatchSize {
+		return maxAnilistBatchSize
+	}
+	return batchSize / 2
+}
+
+// isComplexityError reports whether AniList rejected the query as too complex.
+func isComplexityError(err error) bool {
+	var anilistErr anilistErrorResponse
+	if !errors.As(err, &anilistErr) {
+		return false
+	}
+	for _, e := range anilistErr.Errors {
+		if strings.Contains(strings.ToLower(e.Message), "complexity") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *AnilistClient) fetchMediaListCollection(
+	ctx context.Context,
+	mediaType verniy.MediaType,
+	fields []verniy.MediaListGroupField,
+	batchSize int,
+) ([]verniy.MediaListGroup, []string, error) {
+	query := verniy.FieldObject("query", verniy.QueryParam{
+		"$username": "String",
+		"$type":     "MediaType",
+		"$chunk":    "Int",
+		"$perChunk": "Int",
+		"$status":   "MediaListStatus",
+	}, verniy.FieldObject("MediaListCollection", verniy.QueryParam{
+		"userName": "$username",
+		"type":     "$type",
+		"chunk":    "$chunk",
+		"perChunk": "$perChunk",
+		"status":   "$status",
+	},
+		string(verniy.MediaListCollectionFieldHasNextChunk),
+		string(verniy.MediaListCollectionFieldLists(fields[0], fields[1:]...)),
+	))
+
+	var (
+		groups   []verniy.MediaListGroup
+		warnings []string
 	)
+	for chunk := 1; ; chunk++ {
+		variables := map[string]any{
+			"username": c.username,
+			"type":     mediaType,
+		}
+		if batchSize > 0 {
+			DPrintf("Fetching AniList %s list chunk %d", mediaType, chunk)
+			variables["chunk"] = chunk
+			variables["perChunk"] = batchSize
+		}
+		if c.listStatus != "" {
+			variables["status"] = c.listStatus
+		}
+
+		body, err := json.Marshal(map[string]any{
+			"query":     query,
+			"variables": variables,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, code, err := c.c.MakeRequest(ctx, body)
+		if err != nil {
+			return nil, nil, newSyncError(err)
+		}
+
+		var resp mediaListCollectionResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			if code != http.StatusOK {
+				return nil, nil, fmt.Errorf("unexpected status code %d", code)
+			}
+			return nil, nil, err
+		}
+
+		collection := resp.Data.MediaListCollection
+		if collection == nil {
+			if len(resp.Errors) > 0 {
+				return nil, nil, newSyncError(resp.anilistErrorResponse)
+			}
+			if code != http.StatusOK {
+				return nil, nil, fmt.Errorf("unexpected status code %d", code)
+			}
+			return nil, nil, errors.New("media list collection is empty")
+		}
+
+		if len(resp.Errors) > 0 {
+			warning := fmt.Sprintf("AniList returned partial %s list: %v", mediaType, resp.anilistErrorResponse)
+			log.Printf("Warning: %s", warning)
+			warnings = append(warnings, warning)
+		}
+
+		groups = append(groups, collection.Lists...)
+
+		// The whole list is fetched by a single request, AniList may still report the next chunk.
+		if batchSize <= 0 || collection.HasNextChunk == nil || !(*collection.HasNextChunk) {
+			break
+		}
+	}
+
+	return groups, warnings, nil
 }
 
 func NewAnilistOAuth(ctx context.Context, config Config) (*OAuth, error) {
@@ -104,3 +443,38 @@ func NewAnilistOAuth(ctx context.Context, config Config) (*OAuth, error) {
 
 	return oauthAnilist, nil
 }
+
+// anilistListStatuses are statuses of AniList lists by their default names,
+// these lists can be fetched alone, custom lists are filtered after fetching.
+var anilistListStatuses = map[string]verniy.MediaListStatus{
+	"watching":   verniy.MediaListStatusCurrent,
+	"reading":    verniy.MediaListStatusCurrent,
+	"completed":  verniy.MediaListStatusCompleted,
+	"planning":   verniy.MediaListStatusPlanning,
+	"dropped":    verniy.MediaListStatusDropped,
+	"paused":     verniy.MediaListStatusPaused,
+	"rewatching": verniy.MediaListStatusRepeating,
+	"rereading":  verniy.MediaListStatusRepeating,
+}
+
+// filterAnilistLists keeps the list with the name, lists of a status are matched by status,
+// so split completed lists, e.g. "Completed TV", are kept for "Completed".
+func filterAnilistLists(groups []verniy.MediaListGroup, name string) []verniy.MediaListGroup {
+	if name == "" {
+		return groups
+	}
+
+	status, isStatus := anilistListStatuses[strings.ToLower(name)]
+
+	var res []verniy.MediaListGroup
+	for _, g := range groups {
+		custom := g.IsCustomList != nil && *g.IsCustomList
+		switch {
+		case isStatus && !custom && g.Status != nil && *g.Status == status:
+			res = append(res, g)
+		case !isStatus && custom && g.Name != nil && strings.EqualFold(*g.Name, name):
+			res = append(res, g)
+		}
+	}
+	return res
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rl404/verniy"
+	"golang.org/x/oauth2"
+)
+
+// anilistRequest is the body of AniList GraphQL request.
+type anilistRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// newTestAnilistClient returns a client of the server which replies with the handler result.
+func newTestAnilistClient(t *testing.T, batchSize int, handler func(req anilistRequest) string) *AnilistClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anilistRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("bad request %s: %v", body, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, handler(req))
+	}))
+	t.Cleanup(srv.Close)
+
+	v := verniy.New()
+	v.Host = srv.URL
+	v.Limiter = noLimit{}
+	return &AnilistClient{c: v, username: "someone", batchSize: batchSize}
+}
+
+// noLimit lets tests send requests without the rate limit of AniList.
+type noLimit struct{}
+
+func (noLimit) Take() time.Time { return time.Now() }
+
+// anilistChunk is a list response with an entry per media ID.
+func anilistChunk(hasNext bool, ids ...int) string {
+	var entries []map[string]any
+	for _, id := range ids {
+		entries = append(entries, map[string]any{"status": "CURRENT", "media": map[string]any{"id": id}})
+	}
+	data, _ := json.Marshal(map[string]any{"data": map[string]any{"MediaListCollection": map[string]any{
+		"hasNextChunk": hasNext,
+		"lists":        []any{map[string]any{"entries": entries}},
+	}}})
+	return string(data)
+}
+
+func mediaIDs(groups []verniy.MediaListGroup) []int {
+	var ids []int
+	for _, g := range groups {
+		for _, e := range g.Entries {
+			if e.Media != nil { // failed to resolve
+				ids = append(ids, e.Media.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestGetUserAnimeListByChunks(t *testing.T) {
+	var chunks []any
+	c := newTestAnilistClient(t, 2, func(req anilistRequest) string {
+		chunks = append(chunks, req.Variables["chunk"])
+		if req.Variables["perChunk"] != float64(2) {
+			t.Errorf("got perChunk %v, want 2", req.Variables["perChunk"])
+		}
+		switch req.Variables["chunk"] {
+		case float64(1):
+			return anilistChunk(true, 1, 2)
+		case float64(2):
+			return anilistChunk(true, 3, 4)
+		default:
+			return anilistChunk(false, 5)
+		}
+	})
+
+	groups, _, err := c.GetUserAnimeList(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if got := fmt.Sprint(mediaIDs(groups)); got != "[1 2 3 4 5]" {
+		t.Errorf("got entries %s, want [1 2 3 4 5]", got)
+	}
+	if got := fmt.Sprint(chunks); got != "[1 2 3]" {
+		t.Errorf("got chunks %s, want [1 2 3]", got)
+	}
+}
+
+func TestValidateBatchSize(t *testing.T) {
+	tests := []struct {
+		n       int
+		wantErr bool
+	}{
+		{0, false},
+		{1, false},
+		{maxAnilistBatchSize, false},
+		{-1, true},
+		{maxAnilistBatchSize + 1, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateBatchSize(tt.n); (err != nil) != tt.wantErr {
+			t.Errorf("validateBatchSize(%d) = %v, want error %t", tt.n, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetUserAnimeListPartialErrors(t *testing.T) {
+	c := newTestAnilistClient(t, 0, func(anilistRequest) string {
+		return `{
+			"data": {"MediaListCollection": {"lists": [{"entries": [{"status": "CURRENT", "media": {"id": 1}}, {"status": "CURRENT", "media": null}]}]}},
+			"errors": [{"message": "Media not found", "status": 404}]
+		}`
+	})
+	a := &App{anilist: c, animeUpdater: &Updater{Prefix: "Anime", Statistics: new(Statistics)}}
+
+	groups, err := a.anilistAnimeList(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if got := fmt.Sprint(mediaIDs(groups)); got != "[1]" {
+		t.Errorf("got entries %s, want [1]", got)
+	}
+	if w := a.animeUpdater.Statistics.Warnings; len(w) != 1 || !strings.Contains(w[0], "Media not found") {
+		t.Errorf("got warnings %q, want the partial error", w)
+	}
+}
+
+func TestGetUserAnimeListErrorsWithoutData(t *testing.T) {
+	c := newTestAnilistClient(t, 0, func(anilistRequest) string {
+		return `{"data": {"MediaListCollection": null}, "errors": [{"message": "User not found", "status": 404}]}`
+	})
+
+	if _, _, err := c.GetUserAnimeList(context.Background()); err == nil || !strings.Contains(err.Error(), "User not found") {
+		t.Errorf("got error %v, want User not found", err)
+	}
+}
+
+func TestNewAnilistClientGraphQLURL(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"data": {"Viewer": {"name": "someone"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	oauth := &OAuth{token: &oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(48 * time.Hour)}}
+	c, err := NewAnilistClient(context.Background(), oauth, "someone", srv.URL+"/graphql", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.c.Limiter = noLimit{}
+
+	name, err := c.GetViewerName(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if name != "someone" || auth != "Bearer token" {
+		t.Errorf("got name %q with auth %q, want someone with the token", name, auth)
+	}
+}
+
+func TestGetUserAnimeListWholeListIgnoresNextChunk(t *testing.T) {
+	var requests int
+	c := newTestAnilistClient(t, 0, func(anilistRequest) string {
+		requests++
+		return anilistChunk(true, 1, 2)
+	})
+
+	groups, _, err := c.GetUserAnimeList(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if requests != 1 || fmt.Sprint(mediaIDs(groups)) != "[1 2]" {
+		t.Errorf("got entries %v after %d requests, want [1 2] after 1", mediaIDs(groups), requests)
+	}
+}
+
+func TestGetUserAnimeListComplexity(t *testing.T) {
+	const complexityError = `{"data": {"MediaListCollection": null}, "errors": [{"message": "Max query complexity", "status": 400}]}`
+
+	tests := []struct {
+		name         string
+		maxPerChunk  int // the biggest chunk AniList accepts, 0 if none
+		wantPerChunk string
+		wantErr      bool
+	}{
+		{"succeeds", 100, "[0 500 250 125 62 62]", false},
+		{"gives up", 0, "[0 500 250 125 62 31]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perChunks := []int{}
+			c := newTestAnilistClient(t, 0, func(req anilistRequest) string {
+				perChunk, _ := req.Variables["perChunk"].(float64)
+				perChunks = append(perChunks, int(perChunk))
+				if perChunk == 0 || int(perChunk) > tt.maxPerChunk {
+					return complexityError
+				}
+				if req.Variables["chunk"] == float64(1) {
+					return anilistChunk(true, 1, 2)
+				}
+				return anilistChunk(false, 3)
+			})
+
+			groups, _, err := c.GetUserAnimeList(context.Background())
+			if tt.wantErr {
+				if !isComplexityError(err) {
+					t.Errorf("got error %v, want complexity one", err)
+				}
+			} else if err != nil || fmt.Sprint(mediaIDs(groups)) != "[1 2 3]" {
+				t.Errorf("got entries %v and error %v, want [1 2 3]", mediaIDs(groups), err)
+			}
+			if got := fmt.Sprint(perChunks); got != tt.wantPerChunk {
+				t.Errorf("got chunk sizes %s, want %s", got, tt.wantPerChunk)
+			}
+		})
+	}
+}
+
+func TestGetUserAnimeListScoped(t *testing.T) {
+	tests := []struct {
+		listName   string
+		wantStatus any
+	}{
+		{"Watching", "CURRENT"},
+		{"rewatching", "REPEATING"},
+		{"Favorites", nil}, // custom lists can't be fetched alone
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listName, func(t *testing.T) {
+			var status any
+			c := newTestAnilistClient(t, 0, func(req anilistRequest) string {
+				status = req.Variables["status"]
+				if !strings.Contains(req.Query, "status:$status") {
+					t.Errorf("query %q has no status argument", req.Query)
+				}
+				return anilistChunk(false, 1)
+			})
+			c.listStatus = anilistListStatuses[strings.ToLower(tt.listName)]
+
+			if _, _, err := c.GetUserAnimeList(context.Background()); err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("got status %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFilterAnilistLists(t *testing.T) {
+	list := func(name string, status verniy.MediaListStatus, custom bool, id int) verniy.MediaListGroup {
+		return verniy.MediaListGroup{
+			Name:         &name,
+			Status:       &status,
+			IsCustomList: &custom,
+			Entries:      []verniy.MediaList{{Media: &verniy.Media{ID: id}}},
+		}
+	}
+	groups := []verniy.MediaListGroup{
+		list("Watching", verniy.MediaListStatusCurrent, false, 1),
+		list("Completed TV", verniy.MediaListStatusCompleted, false, 2),
+		list("Completed Movie", verniy.MediaListStatusCompleted, false, 3),
+		list("Favorites", verniy.MediaListStatusCompleted, true, 4),
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "[1 2 3 4]"},
+		{"completed", "[2 3]"},
+		{"favorites", "[4]"},
+		{"Unknown", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(mediaIDs(filterAnilistLists(groups, tt.name))); got != tt.want {
+			t.Errorf("filterAnilistLists(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
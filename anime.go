@@ -51,12 +51,34 @@ type Anime struct {
 	Progress    int
 	Score       float64
 	SeasonYear  int
+	Season      string
 	Status      Status
 	TitleEN     string
 	TitleJP     string
 	TitleRomaji string
 	StartedAt   *time.Time
 	FinishedAt  *time.Time
+	Format      string
+	Genres      []string
+	IsAdult     bool
+	UpdatedAt   *time.Time
+	CreatedAt   *time.Time
+
+	StartedAtInferred  bool
+	FinishedAtInferred bool
+
+	HiddenFromStatusLists bool
+	AnilistStatus         verniy.MediaListStatus // source status before mapping to MAL one
+	AnilistScore          float64                // source score before normalization for MAL
+	Repeating             bool
+	Airing                bool
+	AiredEpisodes         int // known only for airing anime
+	NotesIDMal            int // from "mal:<id>" token in AniList notes
+	CommentsIDAnilist     int // from "anilist:<id>" token in MAL comments
+
+	// MAL only fields, nil for AniList entries, so they are never cleared by the sync.
+	Priority     *int
+	RewatchValue *int
 }
 
 func (a Anime) GetTargetID() TargetID {
@@ -67,7 +89,7 @@ func (a Anime) GetStatusString() string {
 	return string(a.Status)
 }
 
-func (a Anime) GetStringDiffWithTarget(t Target) string {
+func (a Anime) GetStringDiffWithTarget(t Target, o EntryOptions) string {
 	b, ok := t.(Anime)
 	if !ok {
 		return "Diff{undefined}"
@@ -78,7 +100,7 @@ func (a Anime) GetStringDiffWithTarget(t Target) string {
 	if a.Status != b.Status {
 		sb.WriteString(fmt.Sprintf("Status: %s -> %s, ", a.Status, b.Status))
 	}
-	if a.Score != b.Score {
+	if o.syncsScore(a.Score) && a.Score != b.Score {
 		sb.WriteString(fmt.Sprintf("Score: %f -> %f, ", a.Score, b.Score))
 	}
 	if a.Progress != b.Progress {
@@ -87,11 +109,17 @@ func (a Anime) GetStringDiffWithTarget(t Target) string {
 	if a.NumEpisodes != b.NumEpisodes {
 		sb.WriteString(fmt.Sprintf("NumEpisodes: %d -> %d, ", a.NumEpisodes, b.NumEpisodes))
 	}
+	if !sameDates(a.StartedAt, b.StartedAt, o.location()) && !(o.DatesNoClear && a.StartedAt == nil) && a.syncsDates(o) {
+		sb.WriteString(fmt.Sprintf("StartedAt: %s -> %s, ", formatDate(a.StartedAt, a.StartedAtInferred), formatDate(b.StartedAt, false)))
+	}
+	if !sameDates(a.FinishedAt, b.FinishedAt, o.location()) && !(o.DatesNoClear && a.FinishedAt == nil) && a.syncsDates(o) {
+		sb.WriteString(fmt.Sprintf("FinishedAt: %s -> %s, ", formatDate(a.FinishedAt, a.FinishedAtInferred), formatDate(b.FinishedAt, false)))
+	}
 	sb.WriteString("}")
 	return sb.String()
 }
 
-func (a Anime) SameProgressWithTarget(t Target) bool {
+func (a Anime) SameProgressWithTarget(t Target, o EntryOptions) bool {
 	b, ok := t.(Anime)
 	if !ok {
 		return false
@@ -101,7 +129,7 @@ func (a Anime) SameProgressWithTarget(t Target) bool {
 		DPrintf("Status: %s != %s", a.Status, b.Status)
 		return false
 	}
-	if a.Score != b.Score {
+	if o.syncsScore(a.Score) && a.Score != b.Score {
 		DPrintf("Score: %f != %f", a.Score, b.Score)
 		return false
 	}
@@ -141,16 +169,21 @@ func (a Anime) SameTypeWithTarget(t Target) bool {
 		return false
 	}
 
+	if a.IsAdult && !b.IsAdult {
+		DPrintf("Adult source doesn't match non-adult target: %s", b.String())
+		return false
+	}
+
 	eq := func(s1, s2 string) bool {
 		if len(s1) < len(s2) {
-			return strings.Contains(strings.ToLower(s2), strings.ToLower(s1))
+			return strings.Contains(s2, s1)
 		}
-		return strings.Contains(strings.ToLower(s1), strings.ToLower(s2))
+		return strings.Contains(s1, s2)
 	}
 
-	titlesEq := eq(a.TitleEN, b.TitleEN)
+	titlesEq := eq(normalizeTitle(a.TitleEN), normalizeTitle(b.TitleEN))
 	if !titlesEq {
-		titlesEq = eq(a.TitleJP, b.TitleJP)
+		titlesEq = eq(normalizeRomajiTitle(a.TitleJP), normalizeRomajiTitle(b.TitleJP))
 	}
 
 	if titlesEq {
@@ -175,16 +208,16 @@ func (a Anime) SameTypeWithTarget(t Target) bool {
 	}
 
 	// JP
-	aa := strings.ReplaceAll(a.TitleJP, " ", "")
-	bb := strings.ReplaceAll(b.TitleJP, " ", "")
+	aa := strings.ReplaceAll(normalizeRomajiTitle(a.TitleJP), " ", "")
+	bb := strings.ReplaceAll(normalizeRomajiTitle(b.TitleJP), " ", "")
 
 	if f(aa, bb) {
 		return true
 	}
 
 	// EN
-	aa = strings.ReplaceAll(a.TitleEN, " ", "")
-	bb = strings.ReplaceAll(b.TitleEN, " ", "")
+	aa = strings.ReplaceAll(normalizeTitle(a.TitleEN), " ", "")
+	bb = strings.ReplaceAll(normalizeTitle(b.TitleEN), " ", "")
 
 	if f(aa, bb) {
 		return true
@@ -196,29 +229,174 @@ func (a Anime) SameTypeWithTarget(t Target) bool {
 	return f(aa, bb)
 }
 
-func (a Anime) GetUpdateOptions() []mal.UpdateMyAnimeListStatusOption {
+// MatchScoreWithTarget ranks title search candidates, the higher the better.
+func (a Anime) MatchScoreWithTarget(t Target) float64 {
+	b, ok := t.(Anime)
+	if !ok {
+		return 0
+	}
+
+	score := titleSimilarity(
+		[2]string{a.TitleEN, b.TitleEN},
+		[2]string{normalizeRomajiTitle(a.TitleJP), normalizeRomajiTitle(b.TitleJP)},
+		[2]string{normalizeRomajiTitle(a.TitleRomaji), normalizeRomajiTitle(b.TitleEN)},
+	)
+	score += closeness(a.SeasonYear, b.SeasonYear)
+	score += closeness(a.NumEpisodes, b.NumEpisodes)
+	if sameFormat(a.Format, b.Format) {
+		score++
+	}
+	return score
+}
+
+// MergeWithTarget replaces inferred dates by the real ones from the target.
+func (a Anime) MergeWithTarget(t Target) Source {
+	b, ok := t.(Anime)
+	if !ok {
+		return a
+	}
+
+	if a.StartedAtInferred && b.StartedAt != nil {
+		a.StartedAt, a.StartedAtInferred = b.StartedAt, false
+	}
+	if a.FinishedAtInferred && b.FinishedAt != nil {
+		a.FinishedAt, a.FinishedAtInferred = b.FinishedAt, false
+	}
+
+	return a
+}
+
+// WithFieldAuthority takes the fields with MAL authority from the target.
+func (a Anime) WithFieldAuthority(t Target, c FieldAuthorityConfig) Source {
+	b, ok := t.(Anime)
+	if !ok {
+		return a
+	}
+
+	if useTarget(c.Status, 0, 0, a.UpdatedAt, b.UpdatedAt) {
+		a.Status = b.Status
+	}
+	if useTarget(c.Score, a.Score, b.Score, a.UpdatedAt, b.UpdatedAt) {
+		a.Score = b.Score
+	}
+	if useTarget(c.Progress, float64(a.Progress), float64(b.Progress), a.UpdatedAt, b.UpdatedAt) {
+		a.Progress = b.Progress
+	}
+	if useTarget(c.Dates, 0, 0, a.UpdatedAt, b.UpdatedAt) {
+		a.StartedAt, a.StartedAtInferred = b.StartedAt, false
+		a.FinishedAt, a.FinishedAtInferred = b.FinishedAt, false
+	}
+
+	return a
+}
+
+// WithoutProgressRegress keeps the target progress when it is ahead of the source.
+// It reports whether the source progress was replaced.
+func (a Anime) WithoutProgressRegress(t Target) (Source, bool) {
+	b, ok := t.(Anime)
+	if !ok || b.Progress <= a.Progress {
+		return a, false
+	}
+
+	DPrintf("Progress: target is ahead: %d > %d", b.Progress, a.Progress)
+	a.Progress = b.Progress
+	return a, true
+}
+
+// withProgressCappedToAired limits progress of airing anime by the number of aired episodes.
+// It reports whether the progress was capped.
+func (a Anime) withProgressCappedToAired() (Anime, bool) {
+	if !a.Airing || a.AiredEpisodes <= 0 || a.Progress <= a.AiredEpisodes {
+		return a, false
+	}
+	DPrintf("Progress is ahead of aired episodes for %s: %d > %d", a.GetTitle(), a.Progress, a.AiredEpisodes)
+	a.Progress = a.AiredEpisodes
+	return a, true
+}
+
+// withNotesMapping uses MAL ID from AniList notes instead of the one from AniList database.
+func (a Anime) withNotesMapping() Anime {
+	if a.NotesIDMal == 0 || a.NotesIDMal == a.IDMal {
+		return a
+	}
+	log.Printf("Using MAL id %d from notes instead of %d: %s", a.NotesIDMal, a.IDMal, a.GetTitle())
+	a.IDMal = a.NotesIDMal
+	return a
+}
+
+// withRepeatingAsCompleted marks rewatching anime as completed with all episodes watched.
+func (a Anime) withRepeatingAsCompleted() Anime {
+	if !a.Repeating {
+		return a
+	}
+	a.Status = StatusCompleted
+	if a.NumEpisodes > 0 {
+		a.Progress = a.NumEpisodes
+	}
+	return a
+}
+
+// withInferredDates fills missing finish date of completed anime by the last update time
+// and missing start date of watching anime by the time it was added to the list.
+func (a Anime) withInferredDates(loc *time.Location) Anime {
+	if a.Status == StatusCompleted && a.FinishedAt == nil && a.UpdatedAt != nil {
+		d := dateIn(*a.UpdatedAt, loc)
+		a.FinishedAt, a.FinishedAtInferred = &d, true
+		DPrintf("Inferred finish date for %s: %s", a.GetTitle(), d.Format(time.DateOnly))
+	}
+	if a.Status == StatusWatching && a.Progress > 0 && a.StartedAt == nil && a.CreatedAt != nil {
+		d := dateIn(*a.CreatedAt, loc)
+		a.StartedAt, a.StartedAtInferred = &d, true
+		DPrintf("Inferred start date for %s: %s", a.GetTitle(), d.Format(time.DateOnly))
+	}
+	return a
+}
+
+// syncsDates reports whether start and finish dates of the entry are synced.
+func (a Anime) syncsDates(o EntryOptions) bool {
+	return !o.DatesTerminalOnly || a.Status == StatusCompleted || a.Status == StatusDropped
+}
+
+func (a Anime) GetUpdateOptions(o EntryOptions) []mal.UpdateMyAnimeListStatusOption {
 	st, err := a.Status.GetMalStatus()
 	if err != nil {
 		log.Printf("Error getting MAL status: %v", err)
 		return nil
 	}
 
+	if o.OnlyStatusChanges {
+		return []mal.UpdateMyAnimeListStatusOption{st}
+	}
+
 	opts := []mal.UpdateMyAnimeListStatusOption{
 		st,
-		mal.Score(a.Score),
 		mal.NumEpisodesWatched(a.Progress),
 	}
 
-	if a.StartedAt != nil {
-		opts = append(opts, mal.StartDate(*a.StartedAt))
-	} else {
-		opts = append(opts, mal.StartDate(time.Time{}))
+	if o.syncsScore(a.Score) {
+		opts = append(opts, mal.Score(a.Score))
 	}
 
-	if a.Status == StatusCompleted && a.FinishedAt != nil {
-		opts = append(opts, mal.FinishDate(*a.FinishedAt))
-	} else {
-		opts = append(opts, mal.FinishDate(time.Time{}))
+	if a.syncsDates(o) {
+		if a.StartedAt != nil {
+			opts = append(opts, mal.StartDate(a.StartedAt.In(o.location())))
+		} else if !o.DatesNoClear {
+			opts = append(opts, mal.StartDate(time.Time{}))
+		}
+
+		if a.Status == StatusCompleted && a.FinishedAt != nil {
+			opts = append(opts, mal.FinishDate(a.FinishedAt.In(o.location())))
+		} else if !o.DatesNoClear {
+			opts = append(opts, mal.FinishDate(time.Time{}))
+		}
+	}
+
+	if a.Priority != nil {
+		opts = append(opts, mal.Priority(*a.Priority))
+	}
+
+	if a.RewatchValue != nil {
+		opts = append(opts, mal.RewatchValue(*a.RewatchValue))
 	}
 
 	return opts
@@ -246,17 +424,18 @@ func (a Anime) String() string {
 	sb.WriteString(fmt.Sprintf("Progress: %d, ", a.Progress))
 	sb.WriteString(fmt.Sprintf("EpisodeNumber: %d, ", a.NumEpisodes))
 	sb.WriteString(fmt.Sprintf("SeasonYear: %d, ", a.SeasonYear))
+	sb.WriteString(fmt.Sprintf("Season: %s, ", a.Season))
 	sb.WriteString(fmt.Sprintf("StartedAt: %s, ", a.StartedAt))
 	sb.WriteString(fmt.Sprintf("FinishedAt: %s", a.FinishedAt))
 	sb.WriteString("}")
 	return sb.String()
 }
 
-func newAnimesFromMediaListGroups(groups []verniy.MediaListGroup) []Anime {
+func newAnimesFromMediaListGroups(groups []verniy.MediaListGroup, loc *time.Location) []Anime {
 	res := make([]Anime, 0, len(groups))
 	for _, group := range groups {
 		for _, mediaList := range group.Entries {
-			a, err := newAnimeFromMediaListEntry(mediaList)
+			a, err := newAnimeFromMediaListEntry(mediaList, loc)
 			if err != nil {
 				log.Printf("Error creating anime from media list entry: %v", err)
 				continue
@@ -268,7 +447,7 @@ func newAnimesFromMediaListGroups(groups []verniy.MediaListGroup) []Anime {
 	return res
 }
 
-func newAnimeFromMediaListEntry(mediaList verniy.MediaList) (Anime, error) {
+func newAnimeFromMediaListEntry(mediaList verniy.MediaList, loc *time.Location) (Anime, error) {
 	if mediaList.Media == nil {
 		return Anime{}, errors.New("media is nil")
 	}
@@ -311,6 +490,18 @@ func newAnimeFromMediaListEntry(mediaList verniy.MediaList) (Anime, error) {
 		year = *mediaList.Media.SeasonYear
 	}
 
+	var season string
+	if mediaList.Media.Season != nil {
+		season = strings.ToLower(string(*mediaList.Media.Season))
+	}
+
+	airing := mediaList.Media.Status != nil && *mediaList.Media.Status == verniy.MediaStatusReleasing
+
+	var airedEpisodes int
+	if airing && mediaList.Media.NextAiringEpisode != nil {
+		airedEpisodes = mediaList.Media.NextAiringEpisode.Episode - 1
+	}
+
 	var idMal int
 	if mediaList.Media.IDMAL != nil {
 		idMal = *mediaList.Media.IDMAL
@@ -321,8 +512,13 @@ func newAnimeFromMediaListEntry(mediaList verniy.MediaList) (Anime, error) {
 		romajiTitle = *mediaList.Media.Title.Romaji
 	}
 
-	startedAt := convertFuzzyDateToTimeOrNow(mediaList.StartedAt)
-	finishedAt := convertFuzzyDateToTimeOrNow(mediaList.CompletedAt)
+	var format string
+	if mediaList.Media.Format != nil {
+		format = string(*mediaList.Media.Format)
+	}
+
+	startedAt := convertFuzzyDateToTimeOrNow(mediaList.StartedAt, loc)
+	finishedAt := convertFuzzyDateToTimeOrNow(mediaList.CompletedAt, loc)
 
 	return Anime{
 		NumEpisodes: episodeNumber,
@@ -331,19 +527,32 @@ func newAnimeFromMediaListEntry(mediaList verniy.MediaList) (Anime, error) {
 		Progress:    progress,
 		Score:       score,
 		SeasonYear:  year,
+		Season:      season,
 		Status:      mapVerniyStatusToStatus(*mediaList.Status),
 		TitleEN:     titleEN,
 		TitleJP:     titleJP,
 		TitleRomaji: romajiTitle,
 		StartedAt:   startedAt,
 		FinishedAt:  finishedAt,
+		Format:      format,
+		Genres:      mediaList.Media.Genres,
+		IsAdult:     mediaList.Media.IsAdult != nil && *mediaList.Media.IsAdult,
+		UpdatedAt:   convertUnixToTime(mediaList.UpdatedAt),
+		CreatedAt:   convertUnixToTime(mediaList.CreatedAt),
+
+		HiddenFromStatusLists: mediaList.HiddenFromStatusLists != nil && *mediaList.HiddenFromStatusLists,
+		AnilistStatus:         *mediaList.Status,
+		Repeating:             *mediaList.Status == verniy.MediaListStatusRepeating,
+		Airing:                airing,
+		AiredEpisodes:         airedEpisodes,
+		NotesIDMal:            malIDFromNotes(mediaList.Notes),
 	}, nil
 }
 
-func newAnimesFromMalAnimes(malAnimes []mal.Anime) []Anime {
+func newAnimesFromMalAnimes(malAnimes []mal.Anime, loc *time.Location) []Anime {
 	res := make([]Anime, 0, len(malAnimes))
 	for _, malAnime := range malAnimes {
-		a, err := newAnimeFromMalAnime(malAnime)
+		a, err := newAnimeFromMalAnime(malAnime, loc)
 		if err != nil {
 			log.Printf("failed to convert mal anime to anime: %v", err)
 			continue
@@ -353,10 +562,11 @@ func newAnimesFromMalAnimes(malAnimes []mal.Anime) []Anime {
 	return res
 }
 
-func newAnimesFromMalUserAnimes(malAnimes []mal.UserAnime) []Anime {
+func newAnimesFromMalUserAnimes(malAnimes []mal.UserAnime, loc *time.Location) []Anime {
 	res := make([]Anime, 0, len(malAnimes))
 	for _, malAnime := range malAnimes {
-		a, err := newAnimeFromMalAnime(malAnime.Anime)
+		malAnime.Anime.MyListStatus = malAnime.Status
+		a, err := newAnimeFromMalAnime(malAnime.Anime, loc)
 		if err != nil {
 			log.Printf("failed to convert mal anime to anime: %v", err)
 			continue
@@ -366,13 +576,18 @@ func newAnimesFromMalUserAnimes(malAnimes []mal.UserAnime) []Anime {
 	return res
 }
 
-func newAnimeFromMalAnime(malAnime mal.Anime) (Anime, error) {
+func newAnimeFromMalAnime(malAnime mal.Anime, loc *time.Location) (Anime, error) {
 	if malAnime.ID == 0 {
 		return Anime{}, errors.New("ID is nil")
 	}
 
-	startedAt := parseDateOrNow(malAnime.MyListStatus.StartDate)
-	finishedAt := parseDateOrNow(malAnime.MyListStatus.FinishDate)
+	startedAt := parseDateOrNow(malAnime.MyListStatus.StartDate, loc)
+	finishedAt := parseDateOrNow(malAnime.MyListStatus.FinishDate, loc)
+
+	var updatedAt *time.Time
+	if !malAnime.MyListStatus.UpdatedAt.IsZero() {
+		updatedAt = &malAnime.MyListStatus.UpdatedAt
+	}
 
 	titleEN := malAnime.Title
 	if malAnime.AlternativeTitles.En != "" {
@@ -384,6 +599,11 @@ func newAnimeFromMalAnime(malAnime mal.Anime) (Anime, error) {
 		titleJP = malAnime.AlternativeTitles.Ja
 	}
 
+	var priority, rewatchValue *int
+	if malAnime.MyListStatus.Status != "" {
+		priority, rewatchValue = &malAnime.MyListStatus.Priority, &malAnime.MyListStatus.RewatchValue
+	}
+
 	return Anime{
 		NumEpisodes: malAnime.NumEpisodes,
 		IDAnilist:   -1,
@@ -391,11 +611,20 @@ func newAnimeFromMalAnime(malAnime mal.Anime) (Anime, error) {
 		Progress:    malAnime.MyListStatus.NumEpisodesWatched,
 		Score:       float64(malAnime.MyListStatus.Score),
 		SeasonYear:  malAnime.StartSeason.Year,
+		Season:      malAnime.StartSeason.Season,
 		Status:      mapMalAnimeStatusToStatus(malAnime.MyListStatus.Status),
 		TitleEN:     titleEN,
 		TitleJP:     titleJP,
 		StartedAt:   startedAt,
 		FinishedAt:  finishedAt,
+		Format:      malAnime.MediaType,
+		IsAdult:     malAnime.NSFW == malNSFWBlack,
+		UpdatedAt:   updatedAt,
+
+		CommentsIDAnilist: anilistIDFromComments(malAnime.MyListStatus.Comments),
+
+		Priority:     priority,
+		RewatchValue: rewatchValue,
 	}, nil
 }
 
@@ -435,7 +664,7 @@ func mapMalAnimeStatusToStatus(s mal.AnimeStatus) Status {
 	}
 }
 
-func convertFuzzyDateToTimeOrNow(fd *verniy.FuzzyDate) *time.Time {
+func convertFuzzyDateToTimeOrNow(fd *verniy.FuzzyDate, loc *time.Location) *time.Time {
 	if fd == nil || fd.Year == nil || fd.Month == nil || fd.Day == nil {
 		return nil
 	}
@@ -444,20 +673,55 @@ func convertFuzzyDateToTimeOrNow(fd *verniy.FuzzyDate) *time.Time {
 		time.Month(*fd.Month),
 		*fd.Day,
 		0, 0, 0, 0,
-		time.UTC,
+		loc,
 	)
 	return &d
 }
 
-func parseDateOrNow(dateStr string) *time.Time {
+func convertUnixToTime(sec *int) *time.Time {
+	if sec == nil || *sec == 0 {
+		return nil
+	}
+	t := time.Unix(int64(*sec), 0).UTC()
+	return &t
+}
+
+// dateIn returns the calendar date of t in loc as midnight in loc,
+// the form list dates of both services are stored in, see DatesConfig.Timezone.
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// sameDates compares calendar dates in loc, times within the day don't matter.
+func sameDates(a, b *time.Time, loc *time.Location) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func formatDate(t *time.Time, inferred bool) string {
+	if t == nil {
+		return "none"
+	}
+	if inferred {
+		return t.Format(time.DateOnly) + " (inferred)"
+	}
+	return t.Format(time.DateOnly)
+}
+
+// parseDateOrNow parses MAL list date as midnight in loc.
+func parseDateOrNow(dateStr string, loc *time.Location) *time.Time {
 	if dateStr == "" {
 		return nil
 	}
-	parsedTime, err := time.Parse(time.DateOnly, dateStr)
+	parsedTime, err := time.ParseInLocation(time.DateOnly, dateStr, loc)
 	if err != nil {
 		return nil
 	}
-	parsedTime = parsedTime.UTC().Truncate(24 * time.Hour)
 	return &parsedTime
 }
 
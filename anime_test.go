@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+// utc9 is the zone of a user in Tokyo, whose evening is the morning of the same day in UTC
+// and whose night after midnight is still the previous day in UTC.
+var utc9 = time.FixedZone("UTC+9", 9*60*60)
+
+func TestDatesInTimezone(t *testing.T) {
+	// Finished at 00:30 on March 16 in Tokyo, it is 15:30 on March 15 in UTC.
+	finished := time.Date(2024, 3, 16, 0, 30, 0, 0, utc9)
+	updatedAt := finished.UTC()
+
+	a := Anime{IDMal: 1, Status: StatusCompleted, Progress: 12, UpdatedAt: &updatedAt}.withInferredDates(utc9)
+	if got := a.FinishedAt.Format(time.DateOnly); got != "2024-03-16" {
+		t.Errorf("inferred finish date is %s, want 2024-03-16", got)
+	}
+
+	var sent string
+	for _, opt := range a.GetUpdateOptions(EntryOptions{DatesLocation: utc9}) {
+		if d, ok := opt.(mal.FinishDate); ok {
+			sent = time.Time(d).Format(time.DateOnly)
+		}
+	}
+	if sent != "2024-03-16" {
+		t.Errorf("sent finish date is %q, want 2024-03-16", sent)
+	}
+
+	// MAL returns the date sent before, it must be the same as the AniList one.
+	year, month, day := 2024, 3, 16
+	anilist := convertFuzzyDateToTimeOrNow(&verniy.FuzzyDate{Year: &year, Month: &month, Day: &day}, utc9)
+	malDate := parseDateOrNow("2024-03-16", utc9)
+	if !sameDates(anilist, malDate, utc9) {
+		t.Errorf("AniList date %s isn't the same as MAL date %s", anilist, malDate)
+	}
+	if !sameDates(malDate, &finished, utc9) {
+		t.Errorf("MAL date %s isn't the same day as %s", malDate, finished)
+	}
+	if got := dateIn(updatedAt, time.UTC).Format(time.DateOnly); got != "2024-03-15" {
+		t.Errorf("the day of %s in UTC is %s, want 2024-03-15", updatedAt, got)
+	}
+}
+
+func TestSameDates(t *testing.T) {
+	d := time.Date(2024, 3, 16, 0, 0, 0, 0, utc9)
+	evening := time.Date(2024, 3, 16, 23, 59, 0, 0, utc9)
+	nextDay := time.Date(2024, 3, 17, 0, 0, 0, 0, utc9)
+
+	tests := []struct {
+		name string
+		a, b *time.Time
+		want bool
+	}{
+		{"both nil", nil, nil, true},
+		{"one nil", &d, nil, false},
+		{"same day", &d, &evening, true},
+		{"next day", &d, &nextDay, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameDates(tt.a, tt.b, utc9); got != tt.want {
+				t.Errorf("sameDates() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepeatingAsCompleted(t *testing.T) {
+	tests := []struct {
+		name         string
+		entry        string
+		wantStatus   Status
+		wantProgress int
+	}{
+		{
+			"repeating",
+			`{"status": "REPEATING", "progress": 4, "media": {"id": 1, "idMal": 1, "title": {"romaji": "Frieren"}, "episodes": 28}}`,
+			StatusCompleted, 28,
+		},
+		{
+			"repeating, unknown episodes",
+			`{"status": "REPEATING", "progress": 4, "media": {"id": 2, "idMal": 2, "title": {"romaji": "One Piece"}}}`,
+			StatusCompleted, 4,
+		},
+		{
+			"watching",
+			`{"status": "CURRENT", "progress": 4, "media": {"id": 3, "idMal": 3, "title": {"romaji": "Dandadan"}, "episodes": 12}}`,
+			StatusWatching, 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := animeFromAnilist(t, tt.entry)
+			if a.Status != StatusWatching {
+				t.Fatalf("got status %s without the flag, want watching", a.Status)
+			}
+
+			a = a.withRepeatingAsCompleted()
+			if a.Status != tt.wantStatus || a.Progress != tt.wantProgress {
+				t.Errorf("got %s at %d episodes, want %s at %d", a.Status, a.Progress, tt.wantStatus, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestRewatchValueSurvivesCycle(t *testing.T) {
+	var list []mal.UserAnime
+	if err := json.Unmarshal([]byte(`[{"node": {"id": 52991, "title": "Sousou no Frieren", "num_episodes": 28},
+		"list_status": {"status": "completed", "num_episodes_watched": 28, "priority": 2, "rewatch_value": 4}}]`), &list); err != nil {
+		t.Fatal(err)
+	}
+	malAnime := newAnimesFromMalUserAnimes(list, time.UTC)[0]
+
+	// The MAL entry goes through a backup and is restored.
+	data, err := json.Marshal(malAnime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Anime
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := malOnlyOptions(restored.GetUpdateOptions(EntryOptions{})); got != "priority 2, rewatch value 4" {
+		t.Errorf("restored entry sends %q, want priority 2, rewatch value 4", got)
+	}
+
+	// The AniList entry of the same anime is synced over it.
+	anilist := animeFromAnilist(t, `{"status": "COMPLETED", "progress": 28, "repeat": 1,
+		"media": {"id": 154587, "idMal": 52991, "title": {"romaji": "Sousou no Frieren"}, "episodes": 28}}`)
+	anilist = anilist.MergeWithTarget(restored).(Anime)
+	if got := malOnlyOptions(anilist.GetUpdateOptions(EntryOptions{})); got != "" {
+		t.Errorf("AniList entry sends %q, want MAL only fields kept", got)
+	}
+}
+
+// malOnlyOptions describes priority and rewatch value options sent to MAL.
+func malOnlyOptions(opts []mal.UpdateMyAnimeListStatusOption) string {
+	var res []string
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case mal.Priority:
+			res = append(res, fmt.Sprintf("priority %d", v))
+		case mal.RewatchValue:
+			res = append(res, fmt.Sprintf("rewatch value %d", v))
+		}
+	}
+	return strings.Join(res, ", ")
+}
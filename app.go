@@ -2,8 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/rl404/verniy"
+	"golang.org/x/sync/errgroup"
 )
 
 type App struct {
@@ -14,9 +22,34 @@ type App struct {
 
 	animeUpdater *Updater
 	mangaUpdater *Updater
+
+	scoreFormat   verniy.ScoreFormat
+	datesLocation *time.Location // of list dates, see DatesConfig.Timezone
+
+	audit *rotatingFile
+
+	partialFailure bool // one of anime and manga syncs failed, see Run
 }
 
 func NewApp(ctx context.Context, config Config) (*App, error) {
+	seasonWindow, err := newSeasonWindow(*startSeason, *endSeason)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing season window: %w", err)
+	}
+
+	order, err := strategyOrder(config.Matching, *noFuzzyTitle, *strictIDOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := newRetryBudget(config.HTTP.RetryBudget)
+	ctx = withRetries(ctx, budget)
+
+	loc, err := config.Dates.location()
+	if err != nil {
+		return nil, err
+	}
+
 	oauthMAL, err := NewMyAnimeListOAuth(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("error creating mal oauth: %w", err)
@@ -31,19 +64,76 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 
 	log.Println("MAL client created")
 
-	oauthAnilist, err := NewAnilistOAuth(ctx, config)
-	if err != nil {
-		return nil, fmt.Errorf("error creating anilist oauth: %w", err)
+	// AniList is only read by the sync, so it isn't needed when its list is read from a fixture.
+	var anilistClient *AnilistClient
+	if *sourceFile == "" {
+		oauthAnilist, err := NewAnilistOAuth(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating anilist oauth: %w", err)
+		}
+
+		log.Println("Got Anilist token")
+
+		anilistClient, err = NewAnilistClient(ctx, oauthAnilist, config.Anilist.Username, config.Anilist.GraphQLURL, *batchSize, *anilistList)
+		if err != nil {
+			return nil, fmt.Errorf("error creating anilist client: %w", err)
+		}
+
+		log.Println("Anilist client created")
 	}
 
-	log.Println("Got Anilist token")
+	if !(*allowUsernameMismatch) {
+		if err := checkUsernames(ctx, config, malClient, anilistClient); err != nil {
+			return nil, err
+		}
+	}
 
-	anilistClient, err := NewAnilistClient(ctx, oauthAnilist, config.Anilist.Username)
-	if err != nil {
-		return nil, fmt.Errorf("error creating anilist client: %w", err)
+	scoreFormat := verniy.ScoreFormat(config.Score.FormatOverride)
+	if scoreFormat == "" {
+		if anilistClient == nil {
+			return nil, errors.New("score.format_override must be set to read AniList list from a file")
+		}
+		scoreFormat, err = anilistClient.GetScoreFormat(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting anilist score format: %w", err)
+		}
+	}
+
+	log.Printf("AniList score format: %s", scoreFormat)
+
+	if len(config.StatusMap) > 0 {
+		if statuses := config.StatusMap.defaultStatuses(); len(statuses) > 0 {
+			log.Printf("status_map: %v keep the default MAL status", statuses)
+		}
+	}
+
+	var audit *rotatingFile
+	if config.Audit.File != "" {
+		audit, err = newRotatingFile(config.Audit.File, int64(config.Audit.MaxSizeMB)<<20, config.Audit.MaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("error opening audit file: %w", err)
+		}
 	}
 
-	log.Println("Anilist client created")
+	preserveScore := config.Score.RoundTripPolicy == scoreRoundTripPreserve
+
+	entryOptions := EntryOptions{
+		OnlyStatusChanges: *onlyStatusChanges,
+		ZeroScoreUnset:    config.Score.TreatZeroAsUnset,
+		DatesNoClear:      config.Dates.NoClear,
+		DatesTerminalOnly: config.Dates.SyncOnlyTerminal,
+		DatesLocation:     loc,
+	}
+
+	var shuffle *rand.Rand
+	if *randomizeOrder {
+		seed := *randomizeSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		log.Printf("Entries are synced in random order, use -randomize-seed %d to repeat it", seed)
+		shuffle = rand.New(rand.NewSource(seed))
+	}
 
 	animeUpdater := &Updater{
 		Prefix:     "Anime",
@@ -52,13 +142,48 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 			"scott pilgrim takes off":       {}, // this anime is not in MAL
 			"bocchi the rock! recap part 2": {}, // this anime is not in MAL
 		},
+		DryRun:    *dryRun,
+		OnlyTitle: *onlyTitle,
+		OnlyNew:   *onlyNew,
+		Explain:   *explain,
+
+		SkipUnknownStatus: config.Sync.SkipUnknownStatus,
+		NoRegressProgress: config.Sync.NoRegressProgress,
+		IncludeHidden:     config.Sync.IncludeHidden,
+		Filters:           config.Filters,
+		FieldAuthority:    config.FieldAuthority,
+		PreserveScore:     preserveScore,
+		MinEntryAge:       config.Sync.MinEntryAge,
+		TitleSource:       config.Display.TitleSource,
+		NoCreateStatuses:  config.Sync.NoCreateStatuses,
+		MergeDuplicates:   config.Matching.MergeDuplicates,
+		CommentsMapping:   config.Matching.NotesMapping,
+		OnInvalidProgress: config.Sync.OnInvalidProgress,
+		FillGapsOnly:      config.Sync.FillGapsOnly,
+		DowngradePolicy:   config.Matching.TitleMatchDowngradePolicy,
+		Create:            config.Create,
+		ScoreFormat:       scoreFormat,
+		SeasonWindow:      seasonWindow,
+		StrategyOrder:     order,
+		NoFuzzyTitle:      *noFuzzyTitle,
+		StrictIDOnly:      *strictIDOnly,
+		AmbiguityMargin:   config.Matching.AmbiguityMargin,
+		AskOnAmbiguous:    config.Matching.AskOnAmbiguous,
+		YearTolerance:     *yearTolerance,
+		Audit:             auditWriter(audit),
+		RetryBudget:       budget,
+
+		NormalizePlanProgress: config.Sync.NormalizePlanProgress,
+		Shuffle:               shuffle,
+
+		EntryOptions: entryOptions,
 
 		GetTargetByIDFunc: func(ctx context.Context, id TargetID) (Target, error) {
 			resp, err := malClient.GetAnimeByID(ctx, int(id))
 			if err != nil {
 				return nil, fmt.Errorf("error getting anime by id: %w", err)
 			}
-			ani, err := newAnimeFromMalAnime(*resp)
+			ani, err := newAnimeFromMalAnime(*resp, loc)
 			if err != nil {
 				return nil, fmt.Errorf("error creating anime from mal anime: %w", err)
 			}
@@ -70,15 +195,15 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 			if err != nil {
 				return nil, fmt.Errorf("error getting anime by name: %w", err)
 			}
-			return newTargetsFromAnimes(newAnimesFromMalAnimes(resp)), nil
+			return newTargetsFromAnimes(newAnimesFromMalAnimes(resp, loc)), nil
 		},
 
-		UpdateTargetBySourceFunc: func(ctx context.Context, id TargetID, src Source) error {
+		UpdateTargetBySourceFunc: func(ctx context.Context, id TargetID, src Source, o EntryOptions) error {
 			a, ok := src.(Anime)
 			if !ok {
 				return fmt.Errorf("source is not an anime")
 			}
-			if err := malClient.UpdateAnimeByIDAndOptions(ctx, int(id), a.GetUpdateOptions()); err != nil {
+			if err := malClient.UpdateAnimeByIDAndOptions(ctx, int(id), a.GetUpdateOptions(o)); err != nil {
 				return fmt.Errorf("error updating anime by id and options: %w", err)
 			}
 			return nil
@@ -89,13 +214,48 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 		Prefix:       "Manga",
 		Statistics:   new(Statistics),
 		IgnoreTitles: map[string]struct{}{},
+		DryRun:       *dryRun,
+		OnlyTitle:    *onlyTitle,
+		OnlyNew:      *onlyNew,
+		Explain:      *explain,
+
+		SkipUnknownStatus: config.Sync.SkipUnknownStatus,
+		NoRegressProgress: config.Sync.NoRegressProgress,
+		IncludeHidden:     config.Sync.IncludeHidden,
+		Filters:           config.Filters,
+		FieldAuthority:    config.FieldAuthority,
+		PreserveScore:     preserveScore,
+		MinEntryAge:       config.Sync.MinEntryAge,
+		TitleSource:       config.Display.TitleSource,
+		NoCreateStatuses:  config.Sync.NoCreateStatuses,
+		MergeDuplicates:   config.Matching.MergeDuplicates,
+		CommentsMapping:   config.Matching.NotesMapping,
+		OnInvalidProgress: config.Sync.OnInvalidProgress,
+		FillGapsOnly:      config.Sync.FillGapsOnly,
+		DowngradePolicy:   config.Matching.TitleMatchDowngradePolicy,
+		Create:            config.Create,
+		ScoreFormat:       scoreFormat,
+		SeasonWindow:      seasonWindow,
+		StrategyOrder:     order,
+		NoFuzzyTitle:      *noFuzzyTitle,
+		StrictIDOnly:      *strictIDOnly,
+		AmbiguityMargin:   config.Matching.AmbiguityMargin,
+		AskOnAmbiguous:    config.Matching.AskOnAmbiguous,
+		YearTolerance:     *yearTolerance,
+		Audit:             auditWriter(audit),
+		RetryBudget:       budget,
+
+		NormalizePlanProgress: config.Sync.NormalizePlanProgress,
+		Shuffle:               shuffle,
+
+		EntryOptions: entryOptions,
 
 		GetTargetByIDFunc: func(ctx context.Context, id TargetID) (Target, error) {
 			resp, err := malClient.GetMangaByID(ctx, int(id))
 			if err != nil {
 				return nil, fmt.Errorf("error getting anime by id: %w", err)
 			}
-			ani, err := newMangaFromMalManga(*resp)
+			ani, err := newMangaFromMalManga(*resp, loc)
 			if err != nil {
 				return nil, fmt.Errorf("error creating anime from mal anime: %w", err)
 			}
@@ -107,15 +267,15 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 			if err != nil {
 				return nil, fmt.Errorf("error getting anime by name: %w", err)
 			}
-			return newTargetsFromMangas(newMangasFromMalMangas(resp)), nil
+			return newTargetsFromMangas(newMangasFromMalMangas(resp, loc)), nil
 		},
 
-		UpdateTargetBySourceFunc: func(ctx context.Context, id TargetID, src Source) error {
+		UpdateTargetBySourceFunc: func(ctx context.Context, id TargetID, src Source, o EntryOptions) error {
 			m, ok := src.(Manga)
 			if !ok {
 				return fmt.Errorf("source is not an anime")
 			}
-			if err := malClient.UpdateMangaByIDAndOptions(ctx, int(id), m.GetUpdateOptions()); err != nil {
+			if err := malClient.UpdateMangaByIDAndOptions(ctx, int(id), m.GetUpdateOptions(o)); err != nil {
 				return fmt.Errorf("error updating anime by id and options: %w", err)
 			}
 			return nil
@@ -128,75 +288,368 @@ func NewApp(ctx context.Context, config Config) (*App, error) {
 		anilist:      anilistClient,
 		animeUpdater: animeUpdater,
 		mangaUpdater: mangaUpdater,
+		audit:        audit,
+		scoreFormat:  scoreFormat,
+
+		datesLocation: loc,
 	}, nil
 }
 
+// auditWriter avoids storing typed nil in the updater interface field.
+func auditWriter(f *rotatingFile) io.Writer {
+	if f == nil {
+		return nil
+	}
+	return f
+}
+
+// SetEvents makes both updaters send sync progress events to ch.
+func (a *App) SetEvents(ch chan<- SyncEvent) {
+	a.animeUpdater.Events = ch
+	a.mangaUpdater.Events = ch
+}
+
+// SetSince makes both updaters skip sources updated before t.
+func (a *App) SetSince(t time.Time) {
+	a.animeUpdater.Since = t
+	a.mangaUpdater.Since = t
+}
+
+// WriteSummaryCSV writes entries processed by both updaters to the CSV file.
+func (a *App) WriteSummaryCSV(path string) error {
+	return writeSummaryCSV(path, a.mangaUpdater.Statistics, a.animeUpdater.Statistics)
+}
+
+// WriteSummaryJSON writes the summary of both updaters to the JSON file.
+func (a *App) WriteSummaryJSON(path string) error {
+	return writeSummaryJSON(path, newSyncSummary(a.animeUpdater.Statistics, a.mangaUpdater.Statistics))
+}
+
+// RunPostSyncHook runs hooks.post_sync command with the summary of both updaters.
+func (a *App) RunPostSyncHook(ctx context.Context) error {
+	return runPostSyncHook(ctx, a.config.Hooks, newSyncSummary(a.animeUpdater.Statistics, a.mangaUpdater.Statistics))
+}
+
+func (a *App) HasErrors() bool {
+	return a.partialFailure || len(a.animeUpdater.Statistics.Errors) > 0 || len(a.mangaUpdater.Statistics.Errors) > 0
+}
+
+func (a *App) HasWarnings() bool {
+	return a.animeUpdater.Statistics.HasWarnings() || a.mangaUpdater.Statistics.HasWarnings()
+}
+
+func (a *App) Close() error {
+	if a.audit != nil {
+		return a.audit.Close()
+	}
+	return nil
+}
+
+// checkUsernames guards against a token of another account,
+// otherwise one account is read and another one is updated.
+// AniList isn't checked without the client, i.e. if its list is read from a file.
+func checkUsernames(ctx context.Context, config Config, malClient *MyAnimeListClient, anilistClient *AnilistClient) error {
+	if config.MyAnimeList.Username != "@me" {
+		name, err := malClient.GetMyName(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting mal user: %w", err)
+		}
+		if !strings.EqualFold(name, config.MyAnimeList.Username) {
+			return fmt.Errorf("mal username mismatch: config has %q, token belongs to %q, use -allow-username-mismatch to ignore", config.MyAnimeList.Username, name)
+		}
+	}
+
+	if anilistClient == nil {
+		return nil
+	}
+
+	name, err := anilistClient.GetViewerName(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting anilist user: %w", err)
+	}
+	if !strings.EqualFold(name, config.Anilist.Username) {
+		return fmt.Errorf("anilist username mismatch: config has %q, token belongs to %q, use -allow-username-mismatch to ignore", config.Anilist.Username, name)
+	}
+
+	return nil
+}
+
+// PartialSyncError is returned by Run with -all if one of anime and manga syncs failed
+// and the other one succeeded.
+type PartialSyncError struct {
+	Err error
+}
+
+func (e *PartialSyncError) Error() string {
+	return fmt.Sprintf("partial sync: %v", e.Err)
+}
+
+func (e *PartialSyncError) Unwrap() error {
+	return e.Err
+}
+
+// Run syncs manga and anime independently, a failure of one doesn't stop the other.
 func (a *App) Run(ctx context.Context) error {
+	var passes int
+	var errs []error
+
 	if *mangaSync || *allSync {
+		passes++
 		if err := a.syncManga(ctx); err != nil {
-			return fmt.Errorf("error syncing manga: %w", err)
+			err = fmt.Errorf("error syncing manga: %w", err)
+			log.Printf("[%s] %v", a.mangaUpdater.Prefix, err)
+			errs = append(errs, err)
 		}
 	}
 
 	if !(*mangaSync) || *allSync {
+		passes++
 		if err := a.syncAnime(ctx); err != nil {
-			return fmt.Errorf("error syncing anime: %w", err)
+			err = fmt.Errorf("error syncing anime: %w", err)
+			log.Printf("[%s] %v", a.animeUpdater.Prefix, err)
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) < passes:
+		a.partialFailure = true
+		return &PartialSyncError{Err: errors.Join(errs...)}
+	default:
+		return errors.Join(errs...)
+	}
 }
 
 func (a *App) syncAnime(ctx context.Context) error {
+	var animes, malAnimes []Anime
+	err := fetchConcurrently(ctx,
+		func(ctx context.Context) (err error) {
+			animes, err = a.anilistAnimes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			malAnimes, err = a.malAnimes(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := a.backup("anime", malAnimes); err != nil {
+		return err
+	}
+
+	srcAnimes := newSourcesFromAnimes(animes)
+	tgtAnimes := newTargetsFromAnimes(malAnimes)
+
+	log.Printf("[%s] Got %d from AniList", a.animeUpdater.Prefix, len(srcAnimes))
+	log.Printf("[%s] Got %d from Mal", a.animeUpdater.Prefix, len(tgtAnimes))
+
+	a.animeUpdater.Update(ctx, srcAnimes, tgtAnimes)
+	if *verify && !(*dryRun) {
+		a.animeUpdater.Verify(ctx)
+	}
+	a.animeUpdater.Statistics.Print(a.animeUpdater.Prefix, a.config.Summary.MaxWarnings)
+
+	if a.animeUpdater.RetryBudget.exhausted() {
+		return errRetryBudgetExhausted
+	}
+	return nil
+}
+
+// anilistAnimes fetches AniList anime list and prepares it for sync.
+func (a *App) anilistAnimes(ctx context.Context) ([]Anime, error) {
 	log.Printf("[%s] Fetching AniList...", a.animeUpdater.Prefix)
 
-	srcList, err := a.anilist.GetUserAnimeList(ctx)
+	srcList, err := a.anilistAnimeList(ctx)
 	if err != nil {
-		return fmt.Errorf("error getting user anime list from anilist: %w", err)
+		return nil, fmt.Errorf("error getting user anime list from anilist: %w", err)
 	}
 
+	animes := newAnimesFromMediaListGroups(srcList, a.datesLocation)
+	if a.config.Matching.NotesMapping {
+		for i := range animes {
+			animes[i] = animes[i].withNotesMapping()
+		}
+	}
+	if *repeatingAsCompleted {
+		for i := range animes {
+			animes[i] = animes[i].withRepeatingAsCompleted()
+		}
+	}
+	for i := range animes {
+		if st, ok := a.config.StatusMap.animeStatus(animes[i].AnilistStatus); ok {
+			animes[i].Status = st
+		}
+	}
+	if a.config.Dates.Infer {
+		for i := range animes {
+			animes[i] = animes[i].withInferredDates(a.datesLocation)
+		}
+	}
+	if a.config.Sync.CapProgressToAired {
+		for i := range animes {
+			var capped bool
+			if animes[i], capped = animes[i].withProgressCappedToAired(); capped {
+				a.animeUpdater.Statistics.AddWarning("progress capped to %d aired episodes: %s", animes[i].AiredEpisodes, a.animeUpdater.title(animes[i]))
+			}
+		}
+	}
+	for i := range animes {
+		animes[i].AnilistScore = animes[i].Score
+		animes[i].Score = normalizeScoreForMAL(animes[i].Score, a.scoreFormat)
+	}
+
+	return animes, nil
+}
+
+func (a *App) malAnimes(ctx context.Context) ([]Anime, error) {
 	log.Printf("[%s] Fetching MAL...", a.animeUpdater.Prefix)
 
-	tgtList, err := a.mal.GetUserAnimeList(ctx)
+	tgtList, err := a.malAnimeList(ctx)
 	if err != nil {
-		return fmt.Errorf("error getting user anime list from mal: %w", err)
+		return nil, fmt.Errorf("error getting user anime list from mal: %w", err)
 	}
 
-	srcAnimes := newSourcesFromAnimes(newAnimesFromMediaListGroups(srcList))
-	tgtAnimes := newTargetsFromAnimes(newAnimesFromMalUserAnimes(tgtList))
+	return newAnimesFromMalUserAnimes(tgtList, a.datesLocation), nil
+}
 
-	log.Printf("[%s] Got %d from AniList", a.animeUpdater.Prefix, len(srcAnimes))
-	log.Printf("[%s] Got %d from Mal", a.animeUpdater.Prefix, len(tgtAnimes))
+func (a *App) syncManga(ctx context.Context) error {
+	var mangas, malMangas []Manga
+	err := fetchConcurrently(ctx,
+		func(ctx context.Context) (err error) {
+			mangas, err = a.anilistMangas(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			malMangas, err = a.malMangas(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
 
-	a.animeUpdater.Update(ctx, srcAnimes, tgtAnimes)
-	a.animeUpdater.Statistics.Print(a.animeUpdater.Prefix)
+	if err := a.backup("manga", malMangas); err != nil {
+		return err
+	}
+
+	srcs := newSourcesFromMangas(mangas)
+	tgts := newTargetsFromMangas(malMangas)
+
+	log.Printf("[%s] Got %d from AniList", a.mangaUpdater.Prefix, len(srcs))
+	log.Printf("[%s] Got %d from Mal", a.mangaUpdater.Prefix, len(tgts))
 
+	a.mangaUpdater.Update(ctx, srcs, tgts)
+	if *verify && !(*dryRun) {
+		a.mangaUpdater.Verify(ctx)
+	}
+	a.mangaUpdater.Statistics.Print(a.mangaUpdater.Prefix, a.config.Summary.MaxWarnings)
+
+	if a.mangaUpdater.RetryBudget.exhausted() {
+		return errRetryBudgetExhausted
+	}
 	return nil
 }
 
-func (a *App) syncManga(ctx context.Context) error {
+// anilistMangas fetches AniList manga list and prepares it for sync.
+func (a *App) anilistMangas(ctx context.Context) ([]Manga, error) {
 	log.Printf("[%s] Fetching AniList...", a.mangaUpdater.Prefix)
 
-	srcList, err := a.anilist.GetUserMangaList(ctx)
+	srcList, err := a.anilistMangaList(ctx)
 	if err != nil {
-		return fmt.Errorf("error getting user anime list from anilist: %w", err)
+		return nil, fmt.Errorf("error getting user anime list from anilist: %w", err)
 	}
 
+	mangas := newMangasFromMediaListGroups(srcList, a.datesLocation)
+	if a.config.Matching.NotesMapping {
+		for i := range mangas {
+			mangas[i] = mangas[i].withNotesMapping()
+		}
+	}
+	if *repeatingAsCompleted {
+		for i := range mangas {
+			mangas[i] = mangas[i].withRereadingAsCompleted()
+		}
+	}
+	for i := range mangas {
+		if st, ok := a.config.StatusMap.mangaStatus(mangas[i].AnilistStatus); ok {
+			mangas[i].Status = st
+		}
+	}
+	if a.config.Dates.Infer {
+		for i := range mangas {
+			mangas[i] = mangas[i].withInferredDates(a.datesLocation)
+		}
+	}
+	for i := range mangas {
+		mangas[i].AnilistScore = mangas[i].Score
+		mangas[i].Score = normalizeScoreForMAL(mangas[i].Score, a.scoreFormat)
+	}
+
+	return mangas, nil
+}
+
+func (a *App) malMangas(ctx context.Context) ([]Manga, error) {
 	log.Printf("[%s] Fetching MAL...", a.mangaUpdater.Prefix)
 
-	tgtList, err := a.mal.GetUserMangaList(ctx)
+	tgtList, err := a.malMangaList(ctx)
 	if err != nil {
-		return fmt.Errorf("error getting user anime list from mal: %w", err)
+		return nil, fmt.Errorf("error getting user anime list from mal: %w", err)
 	}
 
-	srcs := newSourcesFromMangas(newMangasFromMediaListGroups(srcList))
-	tgts := newTargetsFromMangas(newMangasFromMalUserMangas(tgtList))
+	return newMangasFromMalUserMangas(tgtList, a.datesLocation), nil
+}
 
-	log.Printf("[%s] Got %d from AniList", a.mangaUpdater.Prefix, len(srcs))
-	log.Printf("[%s] Got %d from Mal", a.mangaUpdater.Prefix, len(tgts))
+// fetchConcurrently runs fetches at the same time, e.g. AniList and MAL lists.
+// It returns the first error and cancels the context of the other fetches then.
+func fetchConcurrently(ctx context.Context, fetches ...func(context.Context) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, fetch := range fetches {
+		g.Go(func() error { return fetch(ctx) })
+	}
+	return g.Wait()
+}
 
-	a.mangaUpdater.Update(ctx, srcs, tgts)
-	a.mangaUpdater.Statistics.Print(a.mangaUpdater.Prefix)
+// backup saves MAL list before the sync updates it, so it can be restored
+// if the sync goes wrong. Nothing is updated on dry run, so no backup is needed.
+func (a *App) backup(typ string, entries any) error {
+	if !a.config.Backup.Enabled || *dryRun {
+		return nil
+	}
+
+	dir := a.config.Backup.backupDir(a.config.TokenFilePath)
+	path, err := a.config.Backup.writeBackup(dir, typ, entries)
+	if err != nil {
+		return fmt.Errorf("error writing %s backup: %w", typ, err)
+	}
 
+	log.Printf("Backup of MAL %s list: %s", typ, path)
 	return nil
 }
+
+// strategyOrder returns matching.strategy_order without the title search if it is disabled,
+// the order which is left empty matches nothing and is rejected.
+func strategyOrder(cfg MatchingConfig, noFuzzyTitle, strictIDOnly bool) ([]string, error) {
+	if strictIDOnly {
+		return []string{StrategyID}, nil
+	}
+
+	if !noFuzzyTitle {
+		return cfg.StrategyOrder, nil
+	}
+
+	res := make([]string, 0, len(cfg.StrategyOrder))
+	for _, s := range cfg.StrategyOrder {
+		if s != StrategyTitle {
+			res = append(res, s)
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("-no-fuzzy-title leaves matching.strategy_order %v without strategies, add %q to it", cfg.StrategyOrder, StrategyID)
+	}
+	return res, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+func TestFetchConcurrentlyCancelsOnFirstError(t *testing.T) {
+	errAnilist := errors.New("anilist is down")
+
+	err := fetchConcurrently(context.Background(),
+		func(context.Context) error {
+			return errAnilist
+		},
+		func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				t.Error("MAL fetch wasn't canceled")
+				return nil
+			}
+		},
+	)
+	if !errors.Is(err, errAnilist) {
+		t.Errorf("got error %v, want %v", err, errAnilist)
+	}
+}
+
+func TestFetchConcurrently(t *testing.T) {
+	var anilist, mal bool
+	err := fetchConcurrently(context.Background(),
+		func(context.Context) error { anilist = true; return nil },
+		func(context.Context) error { mal = true; return nil },
+	)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if !anilist || !mal {
+		t.Errorf("got fetched anilist %t, mal %t, want both", anilist, mal)
+	}
+}
+
+func TestRunAllPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	src, tgt := filepath.Join(dir, "anilist.json"), filepath.Join(dir, "mal.json")
+	// The anime list is broken, so its fetch fails.
+	if err := os.WriteFile(src, []byte(`{
+		"anime": {"broken": true},
+		"manga": [{"status": "CURRENT", "entries": [{"status": "CURRENT", "progress": 50,
+			"media": {"id": 30002, "idMal": 2, "title": {"romaji": "Berserk", "english": "Berserk"}, "type": "MANGA"}}]}]
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tgt, []byte(`{
+		"manga": [{"node": {"id": 2, "title": "Berserk"}, "list_status": {"status": "reading", "num_chapters_read": 40}}]
+	}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldAll, oldSrc, oldTgt := *allSync, *sourceFile, *targetFile
+	*allSync, *sourceFile, *targetFile = true, src, tgt
+	t.Cleanup(func() { *allSync, *sourceFile, *targetFile = oldAll, oldSrc, oldTgt })
+
+	var updated []TargetID
+	a := &App{
+		animeUpdater: &Updater{Prefix: "Anime", Statistics: new(Statistics)},
+		mangaUpdater: &Updater{
+			Prefix:     "Manga",
+			Statistics: new(Statistics),
+			UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+				updated = append(updated, id)
+				return nil
+			},
+		},
+		scoreFormat:   verniy.ScoreFormatPoint10,
+		datesLocation: time.UTC,
+	}
+
+	err := a.Run(context.Background())
+	var partialErr *PartialSyncError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("got error %v, want partial failure", err)
+	}
+	if len(updated) != 1 || updated[0] != 2 {
+		t.Errorf("got manga updates %v, want [2]", updated)
+	}
+	if !a.HasErrors() {
+		t.Error("partial failure isn't an error of the run")
+	}
+}
+
+func TestStrategyOrderNoFuzzyTitle(t *testing.T) {
+	tests := []struct {
+		order        []string
+		noFuzzyTitle bool
+		strictIDOnly bool
+		want         string
+		wantErr      bool
+	}{
+		{[]string{StrategyID, StrategyTitle}, false, false, "[id title]", false},
+		{[]string{StrategyID, StrategyTitle}, true, false, "[id]", false},
+		{[]string{StrategyTitle}, false, false, "[title]", false},
+		{[]string{StrategyTitle}, true, false, "", true},
+		{[]string{StrategyTitle}, false, true, "[id]", false},
+		{[]string{StrategyTitle}, true, true, "[id]", false},
+	}
+
+	for _, tt := range tests {
+		got, err := strategyOrder(MatchingConfig{StrategyOrder: tt.order}, tt.noFuzzyTitle, tt.strictIDOnly)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("strategyOrder(%v, %t) error %v, want error %t", tt.order, tt.noFuzzyTitle, err, tt.wantErr)
+		}
+		if err == nil && fmt.Sprint(got) != tt.want {
+			t.Errorf("strategyOrder(%v, %t) = %v, want %s", tt.order, tt.noFuzzyTitle, got, tt.want)
+		}
+	}
+}
+
+func TestCheckUsernames(t *testing.T) {
+	malSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/@me" {
+			t.Errorf("unexpected MAL request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id": 1, "name": "Alice"}`)
+	}))
+	t.Cleanup(malSrv.Close)
+	c := mal.NewClient(nil)
+	c.BaseURL, _ = url.Parse(malSrv.URL + "/")
+	malClient := &MyAnimeListClient{c: c}
+
+	anilistClient := newTestAnilistClient(t, 0, func(anilistRequest) string {
+		return `{"data": {"Viewer": {"name": "bob"}}}`
+	})
+
+	tests := []struct {
+		name          string
+		mal, anilist  string
+		wantErrSubstr string
+	}{
+		{"both match", "alice", "Bob", ""},
+		{"mal of any account", "@me", "bob", ""},
+		{"mal mismatch", "carol", "bob", `mal username mismatch: config has "carol", token belongs to "Alice"`},
+		{"anilist mismatch", "alice", "dave", `anilist username mismatch: config has "dave", token belongs to "bob"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{MyAnimeList: SiteConfig{Username: tt.mal}, Anilist: SiteConfig{Username: tt.anilist}}
+			err := checkUsernames(context.Background(), cfg, malClient, anilistClient)
+			if tt.wantErrSubstr == "" {
+				if err != nil {
+					t.Errorf("got error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErrSubstr)
+			}
+		})
+	}
+}
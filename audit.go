@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const directionAnilistToMal = "anilist-to-mal"
+
+type AuditConfig struct {
+	File      string `yaml:"file"`
+	MaxSizeMB int    `yaml:"max_size_mb"`
+	MaxFiles  int    `yaml:"max_files"`
+}
+
+// auditRecord is a single line of the audit file written for every updated entry.
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	IDAnilist int       `json:"id_anilist,omitempty"`
+	IDMal     int       `json:"id_mal"`
+	Diff      string    `json:"diff,omitempty"` // empty on force sync, target is not fetched
+}
+
+func writeAuditRecord(w io.Writer, r auditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func sourceAnilistID(src Source) int {
+	switch v := src.(type) {
+	case Anime:
+		return v.IDAnilist
+	case Manga:
+		return v.IDAnilist
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const backupDirName = "backups"
+
+// backupEncryptedHeader starts encrypted backup files, it is followed by
+// AES-GCM nonce and sealed JSON. Plain backups are JSON.
+var backupEncryptedHeader = []byte("anilist-mal-sync-backup-aes-gcm\n")
+
+type BackupConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Dir      string `yaml:"dir"`
+	MaxFiles int    `yaml:"max_files"`
+	Key      string `yaml:"key"` // hex encoded AES-256 key
+}
+
+func (c BackupConfig) validate() error {
+	if c.MaxFiles < 1 {
+		return errors.New("backup.max_files must be at least 1")
+	}
+	if c.Key == "" {
+		return nil
+	}
+	if _, err := c.key(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c BackupConfig) key() ([]byte, error) {
+	key, err := hex.DecodeString(c.Key)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("backup.key must be 64 hex characters (AES-256 key)")
+	}
+	return key, nil
+}
+
+// backupDir returns the backup directory, by default it lives beside the token file.
+func (c BackupConfig) backupDir(tokenFilePath string) string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	return filepath.Join(filepath.Dir(tokenFilePath), backupDirName)
+}
+
+// backup is a snapshot of MAL list taken before the sync updates it.
+type backup struct {
+	Type    string          `json:"type"` // anime or manga
+	Time    time.Time       `json:"time"`
+	Entries json.RawMessage `json:"entries"`
+}
+
+// writeBackup saves entries to a new timestamped file in dir
+// and removes the oldest backups of the type over MaxFiles.
+func (c BackupConfig) writeBackup(dir, typ string, entries any) (string, error) {
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	data, err := json.MarshalIndent(backup{Type: typ, Time: now, Entries: raw}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	ext := ".json"
+	if c.Key != "" {
+		key, err := c.key()
+		if err != nil {
+			return "", err
+		}
+		if data, err = encryptBackup(key, data); err != nil {
+			return "", err
+		}
+		ext = ".json.enc"
+	}
+
+	path := filepath.Join(dir, typ+"-"+now.UTC().Format("20060102T150405.000Z")+ext)
+	if err := createDirIfNotExists(path); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+
+	return path, removeOldBackups(dir, typ, c.MaxFiles)
+}
+
+func removeOldBackups(dir, typ string, maxFiles int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), typ+"-") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names) // timestamps in names sort chronologically
+
+	for len(names) > maxFiles {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func encryptBackup(key, data []byte) ([]byte, error) {
+	gcm, err := newBackupGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	res := append(slices.Clone(backupEncryptedHeader), nonce...)
+	return gcm.Seal(res, nonce, data, nil), nil
+}
+
+// decryptBackup returns data as is if it has no encrypted header.
+func decryptBackup(key, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, backupEncryptedHeader) {
+		return data, nil
+	}
+	if key == nil {
+		return nil, errors.New("backup is encrypted, set backup.key")
+	}
+
+	gcm, err := newBackupGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[len(backupEncryptedHeader):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("backup is truncated")
+	}
+
+	res, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting backup, wrong backup.key?: %w", err)
+	}
+	return res, nil
+}
+
+func newBackupGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
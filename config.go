@@ -1,7 +1,13 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,19 +17,163 @@ type OAuthConfig struct {
 	RedirectURI string `yaml:"redirect_uri"`
 }
 
+// validate checks that OAuth server listens on the port of redirect URI,
+// otherwise the callback never arrives. URI without explicit port uses the default
+// port of its scheme, it is checked only for localhost, other hosts are expected
+// to be a reverse proxy which forwards the callback to the OAuth server.
+func (c OAuthConfig) validate() error {
+	u, err := url.Parse(c.RedirectURI)
+	if err != nil {
+		return fmt.Errorf("oauth.redirect_uri: %w", err)
+	}
+
+	p := u.Port()
+	if p == "" {
+		if !isLoopbackHost(u.Hostname()) {
+			return nil
+		}
+		p = defaultPorts[u.Scheme]
+	}
+
+	if p != c.Port {
+		return fmt.Errorf(
+			"oauth.redirect_uri %q points to port %s, but OAuth server listens on port %q: "+
+				"set oauth.port (or PORT env) to %s or change the port in redirect_uri and in the AniList and MAL app settings",
+			c.RedirectURI, p, c.Port, p,
+		)
+	}
+
+	return nil
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 type SiteConfig struct {
 	ClientID     string `yaml:"client_id"`
 	ClientSecret string `yaml:"client_secret"`
 	AuthURL      string `yaml:"auth_url"`
 	TokenURL     string `yaml:"token_url"`
 	Username     string `yaml:"username"`
+	GraphQLURL   string `yaml:"graphql_url,omitempty"` // AniList only
+}
+
+const defaultAnilistGraphQLURL = "https://graphql.anilist.co"
+
+func validateGraphQLURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("anilist.graphql_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("anilist.graphql_url: %q is not an absolute http(s) URL", s)
+	}
+	return nil
+}
+
+type SyncConfig struct {
+	SkipUnknownStatus  bool `yaml:"skip_unknown_status"`
+	NoRegressProgress  bool `yaml:"no_regress_progress"`
+	IncludeHidden      bool `yaml:"include_hidden"`
+	CapProgressToAired bool `yaml:"cap_progress_to_aired"`
+	FillGapsOnly       bool `yaml:"fill_gaps_only"`
+
+	MinEntryAge       time.Duration `yaml:"min_entry_age"`       // entries updated more recently are skipped
+	NoCreateStatuses  []string      `yaml:"no_create_statuses"`  // entries missing on MAL with these statuses aren't added
+	OnInvalidProgress string        `yaml:"on_invalid_progress"` // progress above episodes or chapters is clamped, skipped or pushed
+
+	NormalizePlanProgress bool `yaml:"normalize_plan_progress"` // planned entries are synced with no progress
+}
+
+type DatesConfig struct {
+	Infer    bool   `yaml:"infer"`
+	Timezone string `yaml:"timezone"`
+	NoClear  bool   `yaml:"no_clear"`
+
+	SyncOnlyTerminal bool `yaml:"sync_only_terminal"`
+}
+
+func (c DatesConfig) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("dates.timezone: %w", err)
+	}
+	return loc, nil
+}
+
+type ScoreConfig struct {
+	TreatZeroAsUnset bool   `yaml:"treat_zero_as_unset"`
+	FormatOverride   string `yaml:"format_override"`
+	RoundTripPolicy  string `yaml:"round_trip_policy"`
+}
+
+type MatchingConfig struct {
+	StrategyOrder   []string `yaml:"strategy_order"`
+	NotesMapping    bool     `yaml:"notes_mapping"`
+	AmbiguityMargin float64  `yaml:"ambiguity_margin"`
+	AskOnAmbiguous  bool     `yaml:"ask_on_ambiguous"`
+	MergeDuplicates string   `yaml:"merge_duplicates"`
+
+	TitleMatchDowngradePolicy string `yaml:"title_match_downgrade_policy"`
+}
+
+func (c MatchingConfig) validate() error {
+	if len(c.StrategyOrder) == 0 {
+		return errors.New("matching.strategy_order is empty")
+	}
+	for _, s := range c.StrategyOrder {
+		if !slices.Contains(defaultStrategyOrder, s) {
+			return fmt.Errorf("matching.strategy_order: unknown strategy %q, known: %v", s, defaultStrategyOrder)
+		}
+	}
+	if c.AmbiguityMargin < 0 {
+		return errors.New("matching.ambiguity_margin is negative")
+	}
+	if err := validateMergeDuplicates(c.MergeDuplicates); err != nil {
+		return err
+	}
+	return validateDowngradePolicy(c.TitleMatchDowngradePolicy)
+}
+
+type SummaryConfig struct {
+	MaxWarnings int `yaml:"max_warnings"` // of warnings, errors and skip reasons each, 0 prints all
+}
+
+type LogConfig struct {
+	File      string `yaml:"file"`
+	MaxSizeMB int    `yaml:"max_size_mb"`
+	MaxFiles  int    `yaml:"max_files"`
+	FileOnly  bool   `yaml:"file_only"`
 }
 
 type Config struct {
-	OAuth         OAuthConfig `yaml:"oauth"`
-	Anilist       SiteConfig  `yaml:"anilist"`
-	MyAnimeList   SiteConfig  `yaml:"myanimelist"`
-	TokenFilePath string      `yaml:"token_file_path"`
+	OAuth         OAuthConfig    `yaml:"oauth"`
+	Anilist       SiteConfig     `yaml:"anilist"`
+	MyAnimeList   SiteConfig     `yaml:"myanimelist"`
+	TokenFilePath string         `yaml:"token_file_path"`
+	Sync          SyncConfig     `yaml:"sync"`
+	Filters       FiltersConfig  `yaml:"filters"`
+	Dates         DatesConfig    `yaml:"dates"`
+	Matching      MatchingConfig `yaml:"matching"`
+	Log           LogConfig      `yaml:"log"`
+	Score         ScoreConfig    `yaml:"score"`
+	Audit         AuditConfig    `yaml:"audit"`
+	HTTP          HTTPConfig     `yaml:"http"`
+	StatusMap     StatusMap      `yaml:"status_map"`
+	Backup        BackupConfig   `yaml:"backup"`
+
+	FieldAuthority FieldAuthorityConfig `yaml:"field_authority"`
+	Display        DisplayConfig        `yaml:"display"`
+	Hooks          HooksConfig          `yaml:"hooks"`
+	Create         CreateConfig         `yaml:"create"`
+	Summary        SummaryConfig        `yaml:"summary"`
 }
 
 func loadConfigFromFile(filename string) (Config, error) {
@@ -32,16 +182,133 @@ func loadConfigFromFile(filename string) (Config, error) {
 		return Config{}, err
 	}
 
-	var cfg Config
+	cfg := Config{
+		OAuth: OAuthConfig{
+			Port: "18080",
+		},
+		Anilist: SiteConfig{
+			GraphQLURL: defaultAnilistGraphQLURL,
+		},
+		Sync: SyncConfig{
+			SkipUnknownStatus: true,
+			IncludeHidden:     true,
+			OnInvalidProgress: invalidProgressClamp,
+
+			NormalizePlanProgress: true,
+		},
+		Matching: MatchingConfig{
+			StrategyOrder:   defaultStrategyOrder,
+			AmbiguityMargin: defaultAmbiguityMargin,
+
+			TitleMatchDowngradePolicy: downgradeSkip,
+		},
+		Log: LogConfig{
+			MaxSizeMB: 10,
+			MaxFiles:  3,
+		},
+		Audit: AuditConfig{
+			MaxSizeMB: 10,
+			MaxFiles:  3,
+		},
+		Score: ScoreConfig{
+			RoundTripPolicy: scoreRoundTripLossy,
+		},
+		Backup: BackupConfig{
+			MaxFiles: 5,
+		},
+		Display: DisplayConfig{
+			TitleSource: titleSourceEnglish,
+		},
+		Summary: SummaryConfig{
+			MaxWarnings: 20,
+		},
+		Hooks: HooksConfig{
+			Timeout: defaultHookTimeout,
+		},
+		FieldAuthority: FieldAuthorityConfig{
+			Status:   authorityAnilist,
+			Score:    authorityAnilist,
+			Progress: authorityAnilist,
+			Dates:    authorityAnilist,
+		},
+	}
 	err = yaml.Unmarshal(data, &cfg)
 	if err != nil {
 		return Config{}, err
 	}
 
+	if err := cfg.Matching.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateGraphQLURL(cfg.Anilist.GraphQLURL); err != nil {
+		return Config{}, err
+	}
+
+	if _, err := cfg.Dates.location(); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateScoreFormat(cfg.Score.FormatOverride); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.HTTP.RetryBudget < 0 {
+		return Config{}, errors.New("http.retry_budget is negative")
+	}
+
+	if err := validateScoreRoundTripPolicy(cfg.Score.RoundTripPolicy); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.StatusMap.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.Sync.MinEntryAge < 0 {
+		return Config{}, errors.New("sync.min_entry_age is negative")
+	}
+
+	if err := validateInvalidProgressPolicy(cfg.Sync.OnInvalidProgress); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Backup.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.FieldAuthority.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Display.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Hooks.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Create.validate(); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.Summary.MaxWarnings < 0 {
+		return Config{}, errors.New("summary.max_warnings is negative")
+	}
+
 	if port := os.Getenv("PORT"); port != "" {
 		cfg.OAuth.Port = port
 	}
 
+	if cfg.OAuth.RedirectURI == "" {
+		cfg.OAuth.RedirectURI = "http://localhost:" + cfg.OAuth.Port + "/callback"
+	}
+
+	if err := cfg.OAuth.validate(); err != nil {
+		return Config{}, err
+	}
+
 	if clientSecret := os.Getenv("CLIENT_SECRET_ANILIST"); clientSecret != "" {
 		cfg.Anilist.ClientSecret = clientSecret
 	}
@@ -56,3 +323,16 @@ func loadConfigFromFile(filename string) (Config, error) {
 
 	return cfg, nil
 }
+
+func (c Config) redacted() Config {
+	if c.Anilist.ClientSecret != "" {
+		c.Anilist.ClientSecret = "***"
+	}
+	if c.MyAnimeList.ClientSecret != "" {
+		c.MyAnimeList.ClientSecret = "***"
+	}
+	if c.Backup.Key != "" {
+		c.Backup.Key = "***"
+	}
+	return c
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigGraphQLURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"default", "anilist: {username: someone}", defaultAnilistGraphQLURL, false},
+		{"proxy", "anilist: {graphql_url: \"http://127.0.0.1:8080/graphql\"}", "http://127.0.0.1:8080/graphql", false},
+		{"relative", "anilist: {graphql_url: \"/graphql\"}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfigFromFile(writeConfig(t, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && cfg.Anilist.GraphQLURL != tt.want {
+				t.Errorf("got graphql_url %q, want %q", cfg.Anilist.GraphQLURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRedirectURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"derived", `oauth: {port: "18080"}`, "http://localhost:18080/callback", false},
+		{"matching port", `oauth: {port: "18080", redirect_uri: "http://127.0.0.1:18080/callback"}`, "http://127.0.0.1:18080/callback", false},
+		{"mismatched port", `oauth: {port: "18080", redirect_uri: "http://localhost:8080/callback"}`, "", true},
+		{"localhost without port", `oauth: {port: "18080", redirect_uri: "http://localhost/callback"}`, "", true},
+		{"localhost on default port", `oauth: {port: "80", redirect_uri: "http://localhost/callback"}`, "http://localhost/callback", false},
+		{"proxy without port", `oauth: {port: "18080", redirect_uri: "https://sync.example.com/callback"}`, "https://sync.example.com/callback", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", "")
+			cfg, err := loadConfigFromFile(writeConfig(t, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && cfg.OAuth.RedirectURI != tt.want {
+				t.Errorf("got redirect_uri %q, want %q", cfg.OAuth.RedirectURI, tt.want)
+			}
+		})
+	}
+}
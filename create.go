@@ -0,0 +1,46 @@
+package main
+
+import "errors"
+
+// CreateConfig gates creation of entries missing in MAL list, existing entries are always updated.
+type CreateConfig struct {
+	MinProgress int     `yaml:"min_progress"`
+	MinScore    float64 `yaml:"min_score"` // on MAL 0-10 scale
+}
+
+func (c CreateConfig) validate() error {
+	if c.MinProgress < 0 {
+		return errors.New("create.min_progress is negative")
+	}
+	if c.MinScore < 0 || c.MinScore > 10 {
+		return errors.New("create.min_score is out of 0-10 range")
+	}
+	return nil
+}
+
+// belowThreshold reports whether the source reaches none of the set thresholds,
+// e.g. a planned entry with no progress and no score.
+func (c CreateConfig) belowThreshold(src Source) bool {
+	if c.MinProgress == 0 && c.MinScore == 0 {
+		return false
+	}
+	if c.MinProgress > 0 && sourceProgress(src) >= c.MinProgress {
+		return false
+	}
+	if c.MinScore > 0 && sourceScore(src) >= c.MinScore {
+		return false
+	}
+	return true
+}
+
+// sourceScore returns the score of the source on MAL scale, 0 if it isn't rated.
+func sourceScore(src Source) float64 {
+	switch v := src.(type) {
+	case Anime:
+		return v.Score
+	case Manga:
+		return v.Score
+	default:
+		return 0
+	}
+}
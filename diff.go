@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// diffFormatText is the -diff format kept for compatibility, it is the table one.
+const diffFormatText = "text"
+
+// diffFormatter returns the formatter of -diff format, text is the table one.
+func diffFormatter(s string) (Formatter, error) {
+	if s == diffFormatText {
+		s = formatTable
+	}
+	f, err := newFormatter(s)
+	if err != nil {
+		return nil, fmt.Errorf("-diff: %w", err)
+	}
+	return f, nil
+}
+
+// diffRow is a difference between AniList and MAL lists.
+type diffRow struct {
+	Kind      string `json:"kind" yaml:"kind"` // changed, only-anilist or only-mal
+	Type      string `json:"type" yaml:"type"`
+	Title     string `json:"title" yaml:"title"`
+	IDAnilist int    `json:"id_anilist,omitempty" yaml:"id_anilist,omitempty"`
+	IDMal     int    `json:"id_mal,omitempty" yaml:"id_mal,omitempty"`
+	Diff      string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+type diffRows []diffRow
+
+func (diffRows) Header() []string {
+	return []string{"kind", "type", "title", "anilist id", "mal id", "diff"}
+}
+
+func (rows diffRows) Rows() [][]string {
+	res := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, []string{r.Kind, r.Type, r.Title, strconv.Itoa(r.IDAnilist), strconv.Itoa(r.IDMal), r.Diff})
+	}
+	return res
+}
+
+// Diff compares AniList and MAL lists as the sync would and writes the differences,
+// nothing is updated.
+func (a *App) Diff(ctx context.Context, f Formatter, w io.Writer) error {
+	var rows diffRows
+
+	if *mangaSync || *allSync {
+		var mangas, malMangas []Manga
+		err := fetchConcurrently(ctx,
+			func(ctx context.Context) (err error) {
+				mangas, err = a.anilistMangas(ctx)
+				return err
+			},
+			func(ctx context.Context) (err error) {
+				malMangas, err = a.malMangas(ctx)
+				return err
+			},
+		)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, diffUpdater(ctx, a.mangaUpdater, newSourcesFromMangas(mangas), newTargetsFromMangas(malMangas))...)
+	}
+
+	if !(*mangaSync) || *allSync {
+		var animes, malAnimes []Anime
+		err := fetchConcurrently(ctx,
+			func(ctx context.Context) (err error) {
+				animes, err = a.anilistAnimes(ctx)
+				return err
+			},
+			func(ctx context.Context) (err error) {
+				malAnimes, err = a.malAnimes(ctx)
+				return err
+			},
+		)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, diffUpdater(ctx, a.animeUpdater, newSourcesFromAnimes(animes), newTargetsFromAnimes(malAnimes))...)
+	}
+
+	return f.Format(w, rows)
+}
+
+// diffUpdater runs a dry sync on a read-only copy of the updater and collects the differences
+// from its events: dry run skips are changed entries, unmatched sources are only on AniList
+// and targets no source was matched to are only on MAL.
+func diffUpdater(ctx context.Context, u *Updater, srcs []Source, tgts []Target) []diffRow {
+	du := *u
+	du.Statistics = new(Statistics)
+	du.DryRun = true
+
+	events := make(chan SyncEvent)
+	du.Events = events
+
+	// Sources skipped before matching aren't differences, so their targets aren't only on MAL.
+	matched := make(map[TargetID]struct{}, len(srcs))
+	for _, src := range srcs {
+		matched[src.GetTargetID()] = struct{}{}
+	}
+
+	var rows []diffRow
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range events {
+			switch {
+			case e.Kind == SyncEventMatched:
+				matched[e.TargetID] = struct{}{}
+			case e.Kind == SyncEventSkipped && e.Reason == reasonDryRun:
+				rows = append(rows, diffRow{Kind: "changed", Title: e.Title, IDAnilist: e.SourceID, IDMal: int(e.TargetID), Diff: e.Diff})
+			case e.Kind == SyncEventError && errors.Is(e.Err, errTargetNotFound):
+				rows = append(rows, diffRow{Kind: "only-anilist", Title: e.Title, IDAnilist: e.SourceID})
+			}
+		}
+	}()
+
+	du.Update(ctx, srcs, tgts)
+	close(events)
+	<-done
+
+	for _, tgt := range tgts {
+		if _, ok := matched[tgt.GetTargetID()]; !ok {
+			row := diffRow{Kind: "only-mal", IDMal: int(tgt.GetTargetID())}
+			if t, ok := tgt.(interface{ GetTitle() string }); ok {
+				row.Title = t.GetTitle()
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	typ := strings.ToLower(u.Prefix)
+	for i := range rows {
+		rows[i].Type = typ
+	}
+	return rows
+}
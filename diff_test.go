@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiffUpdaterIsReadOnly(t *testing.T) {
+	u := &Updater{
+		Prefix:     "Anime",
+		Statistics: new(Statistics),
+		UpdateTargetBySourceFunc: func(context.Context, TargetID, Source, EntryOptions) error {
+			t.Error("diff updated the target")
+			return nil
+		},
+	}
+	srcs := []Source{Anime{IDAnilist: 10, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching, Progress: 5}}
+	tgts := []Target{
+		Anime{IDAnilist: -1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching, Progress: 3},
+		Anime{IDAnilist: -1, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching},
+	}
+
+	rows := diffUpdater(context.Background(), u, srcs, tgts)
+
+	if *dryRun {
+		t.Error("diff changed the -d flag")
+	}
+	if u.DryRun {
+		t.Error("diff changed the updater")
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2: %+v", len(rows), rows)
+	}
+	if r := rows[0]; r.Kind != "changed" || r.IDMal != 1 || r.Type != "anime" {
+		t.Errorf("got %+v, want changed anime 1", r)
+	}
+	if r := rows[1]; r.Kind != "only-mal" || r.IDMal != 2 || r.Title != "Dandadan" {
+		t.Errorf("got %+v, want only-mal Dandadan", r)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Policies for title matches which would downgrade a completed MAL entry, see allowDowngrade.
+const (
+	downgradeAllow = "allow"
+	downgradeSkip  = "skip"
+	downgradeAsk   = "ask"
+)
+
+func validateDowngradePolicy(policy string) error {
+	switch policy {
+	case "", downgradeAllow, downgradeSkip, downgradeAsk:
+		return nil
+	default:
+		return fmt.Errorf("matching.title_match_downgrade_policy: unknown policy %q, known: %s, %s, %s",
+			policy, downgradeAllow, downgradeSkip, downgradeAsk)
+	}
+}
+
+// downgrade returns the target status and reports whether the source would move
+// a completed target back to planned or dropped, which is suspicious for a match by title.
+func downgrade(src Source, tgt Target) (string, bool) {
+	switch t := tgt.(type) {
+	case Anime:
+		s, ok := src.(Anime)
+		return string(t.Status), ok && t.Status == StatusCompleted && (s.Status == StatusPlanToWatch || s.Status == StatusDropped)
+	case Manga:
+		s, ok := src.(Manga)
+		return string(t.Status), ok && t.Status == MangaStatusCompleted && (s.Status == MangaStatusPlanToRead || s.Status == MangaStatusDropped)
+	default:
+		return "", false
+	}
+}
+
+// allowDowngrade guards against wrong title matches: a target found by title search,
+// not by MAL ID of the source, isn't downgraded without confirmation.
+func (u *Updater) allowDowngrade(src Source, tgt Target) bool {
+	if u.DowngradePolicy == downgradeAllow || u.DowngradePolicy == "" {
+		return true
+	}
+	if tgt.GetTargetID() == src.GetTargetID() {
+		return true
+	}
+	from, ok := downgrade(src, tgt)
+	if !ok {
+		return true
+	}
+
+	u.trace.addf("title match would change status %s to %s", from, src.GetStatusString())
+
+	if u.DowngradePolicy == downgradeAsk && stdinIsTerminal() {
+		question := fmt.Sprintf("[%s] Change status of MAL %s from %s to %s matched by title to %s?",
+			u.Prefix, tgt.String(), from, src.GetStatusString(), src.String())
+		if askYesNo(question) {
+			return true
+		}
+	}
+
+	log.Printf("[%s] Skipping %s: title match would change status %s to %s", u.Prefix, u.title(src), from, src.GetStatusString())
+	u.Statistics.AddWarning("title match would change status %s to %s, review it manually: %s (MAL id %d)",
+		from, src.GetStatusString(), u.title(src), tgt.GetTargetID())
+	return false
+}
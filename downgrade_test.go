@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestAllowDowngrade(t *testing.T) {
+	completed := Anime{IDMal: 100, TitleEN: "Squid Girl", Status: StatusCompleted, Progress: 12}
+
+	tests := []struct {
+		name   string
+		policy string
+		src    Anime
+		want   bool
+	}{
+		{"title match downgrade, skip", downgradeSkip, Anime{IDMal: 0, TitleEN: "Squid Girl", Status: StatusPlanToWatch}, false},
+		{"title match drop, skip", downgradeSkip, Anime{IDMal: 0, TitleEN: "Squid Girl", Status: StatusDropped}, false},
+		{"id match downgrade, skip", downgradeSkip, Anime{IDMal: 100, TitleEN: "Squid Girl", Status: StatusPlanToWatch}, true},
+		{"title match upgrade, skip", downgradeSkip, Anime{IDMal: 0, TitleEN: "Squid Girl", Status: StatusCompleted}, true},
+		{"title match downgrade, allow", downgradeAllow, Anime{IDMal: 0, TitleEN: "Squid Girl", Status: StatusPlanToWatch}, true},
+		{"title match downgrade, default", "", Anime{IDMal: 0, TitleEN: "Squid Girl", Status: StatusPlanToWatch}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Updater{Prefix: "Anime", Statistics: new(Statistics), DowngradePolicy: tt.policy}
+			if got := u.allowDowngrade(tt.src, completed); got != tt.want {
+				t.Errorf("allowDowngrade() = %t, want %t", got, tt.want)
+			}
+			if wantWarns := map[bool]int{true: 0, false: 1}[tt.want]; len(u.Statistics.Warnings) != wantWarns {
+				t.Errorf("got %d warnings, want %d", len(u.Statistics.Warnings), wantWarns)
+			}
+		})
+	}
+}
+
+func TestDowngradeManga(t *testing.T) {
+	tgt := Manga{Status: MangaStatusCompleted}
+	if _, ok := downgrade(Manga{Status: MangaStatusPlanToRead}, tgt); !ok {
+		t.Error("completed to plan to read isn't a downgrade")
+	}
+	if _, ok := downgrade(Manga{Status: MangaStatusOnHold}, tgt); ok {
+		t.Error("completed to on hold is a downgrade")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// Rules to pick the entry of AniList duplicates which is synced, see mergeDuplicates.
+const (
+	mergeDuplicatesProgress = "progress"
+	mergeDuplicatesNewest   = "newest"
+)
+
+func validateMergeDuplicates(rule string) error {
+	switch rule {
+	case "", mergeDuplicatesProgress, mergeDuplicatesNewest:
+		return nil
+	default:
+		return fmt.Errorf("matching.merge_duplicates: unknown rule %q, known: %s, %s",
+			rule, mergeDuplicatesProgress, mergeDuplicatesNewest)
+	}
+}
+
+// sourceProgress returns watched episodes or read chapters of the source.
+func sourceProgress(src Source) int {
+	switch v := src.(type) {
+	case Anime:
+		return v.Progress
+	case Manga:
+		return v.Progress
+	default:
+		return 0
+	}
+}
+
+// duplicateKeys returns keys of the source which are equal for likely duplicates:
+// the same MAL ID or the same normalized title.
+func duplicateKeys(src Source) []string {
+	var keys []string
+	if t := normalizeTitle(src.GetTitle()); t != "" {
+		keys = append(keys, "title:"+t)
+	}
+	if id := src.GetTargetID(); id != 0 {
+		keys = append(keys, "id:"+strconv.Itoa(int(id)))
+	}
+	return keys
+}
+
+// preferDuplicate reports whether the duplicate should be synced instead of the kept source.
+func preferDuplicate(rule string, kept, dup Source) bool {
+	switch rule {
+	case mergeDuplicatesProgress:
+		return sourceProgress(dup) > sourceProgress(kept)
+	case mergeDuplicatesNewest:
+		keptAt, dupAt := sourceUpdatedAt(kept), sourceUpdatedAt(dup)
+		return dupAt != nil && (keptAt == nil || dupAt.After(*keptAt))
+	default:
+		return false
+	}
+}
+
+// mergeDuplicates leaves one source of AniList entries which would update the same MAL entry,
+// e.g. TV and TV Short ones, otherwise they overwrite each other on every sync.
+// Merges are reported as warnings, so duplicates can be cleaned up on AniList.
+func (u *Updater) mergeDuplicates(srcs []Source) []Source {
+	if u.MergeDuplicates == "" {
+		return srcs
+	}
+
+	merged := make([]Source, 0, len(srcs))
+	keptByKey := make(map[string]int) // index in merged
+	for _, src := range srcs {
+		idx := -1
+		for _, k := range duplicateKeys(src) {
+			if i, ok := keptByKey[k]; ok {
+				idx = i
+				break
+			}
+		}
+
+		if idx < 0 {
+			idx = len(merged)
+			merged = append(merged, src)
+		} else {
+			kept := merged[idx]
+			if preferDuplicate(u.MergeDuplicates, kept, src) {
+				merged[idx] = src
+				kept, src = src, kept
+			}
+			log.Printf("[%s] Merged AniList duplicates %q and %q, syncing the first one", u.Prefix, u.title(kept), u.title(src))
+			u.Statistics.AddWarning("AniList duplicates merged: %q (AniList ID %d) is synced, %q (AniList ID %d) is not",
+				u.title(kept), sourceAnilistID(kept), u.title(src), sourceAnilistID(src))
+		}
+
+		for _, k := range duplicateKeys(merged[idx]) {
+			keptByKey[k] = idx
+		}
+		for _, k := range duplicateKeys(src) {
+			keptByKey[k] = idx
+		}
+	}
+
+	return merged
+}
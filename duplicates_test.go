@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	// TV Short entry of the same show has the same MAL ID, the recap has the same title only.
+	tv := Anime{IDAnilist: 1, IDMal: 100, TitleEN: "Frieren", Format: "TV", Status: StatusWatching, Progress: 5, UpdatedAt: &older}
+	short := Anime{IDAnilist: 2, IDMal: 100, TitleEN: "Frieren Minis", Format: "TV_SHORT", Status: StatusWatching, Progress: 8, UpdatedAt: &newer}
+	other := Anime{IDAnilist: 3, IDMal: 300, TitleEN: "Dandadan", Status: StatusWatching, Progress: 1}
+
+	tests := []struct {
+		rule      string
+		wantIDs   []int
+		wantWarns int
+	}{
+		{"", []int{1, 2, 3}, 0},
+		{mergeDuplicatesProgress, []int{2, 3}, 1},
+		{mergeDuplicatesNewest, []int{2, 3}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			u := &Updater{Prefix: "Anime", Statistics: new(Statistics), MergeDuplicates: tt.rule}
+			got := u.mergeDuplicates([]Source{tv, short, other})
+
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d sources, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, src := range got {
+				if id := sourceAnilistID(src); id != tt.wantIDs[i] {
+					t.Errorf("source %d: got AniList ID %d, want %d", i, id, tt.wantIDs[i])
+				}
+			}
+			if n := len(u.Statistics.Warnings); n != tt.wantWarns {
+				t.Errorf("got %d warnings, want %d", n, tt.wantWarns)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicatesUpdatesTargetOnce(t *testing.T) {
+	var updates []Source
+	u := &Updater{
+		Prefix:          "Anime",
+		Statistics:      new(Statistics),
+		MergeDuplicates: mergeDuplicatesProgress,
+		UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, _ EntryOptions) error {
+			updates = append(updates, src)
+			return nil
+		},
+	}
+
+	u.Update(context.Background(), []Source{
+		Anime{IDAnilist: 1, IDMal: 100, TitleEN: "Frieren", Status: StatusWatching, Progress: 5},
+		Anime{IDAnilist: 2, IDMal: 100, TitleEN: "Frieren Minis", Status: StatusWatching, Progress: 8},
+	}, []Target{
+		Anime{IDAnilist: -1, IDMal: 100, TitleEN: "Frieren", Status: StatusWatching, Progress: 3},
+	})
+
+	if len(updates) != 1 {
+		t.Fatalf("got %d updates, want 1", len(updates))
+	}
+	if p := updates[0].(Anime).Progress; p != 8 {
+		t.Errorf("got progress %d, want 8 of the duplicate with more progress", p)
+	}
+}
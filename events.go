@@ -0,0 +1,48 @@
+package main
+
+import "context"
+
+// SyncEventKind is a kind of event emitted by Updater.
+type SyncEventKind string
+
+const (
+	SyncEventStarted  SyncEventKind = "started"
+	SyncEventMatched  SyncEventKind = "matched"
+	SyncEventUpdated  SyncEventKind = "updated"
+	SyncEventSkipped  SyncEventKind = "skipped"
+	SyncEventError    SyncEventKind = "error"
+	SyncEventFinished SyncEventKind = "finished"
+)
+
+// SyncEvent describes progress of the sync for a single entry.
+// Title is empty for started and finished events.
+type SyncEvent struct {
+	Kind     SyncEventKind
+	Prefix   string
+	Title    string
+	SourceID int // AniList ID
+	TargetID TargetID
+	Reason   string // skip reason
+	Diff     string // of updated entries and skipped on dry run
+	Err      error
+}
+
+// emit sends the event to the Events channel if it is set.
+// The send blocks until the event is received or ctx is done, so the consumer must drain the channel.
+func (u *Updater) emit(ctx context.Context, e SyncEvent) {
+	u.explainEvent(e)
+	u.Statistics.addItem(u.Prefix, e)
+
+	if u.Events == nil {
+		return
+	}
+	e.Prefix = u.Prefix
+	select {
+	case u.Events <- e:
+	case <-ctx.Done():
+	}
+}
+
+func (u *Updater) emitSkipped(ctx context.Context, src Source, reason string) {
+	u.emit(ctx, SyncEvent{Kind: SyncEventSkipped, Title: u.title(src), SourceID: sourceAnilistID(src), TargetID: src.GetTargetID(), Reason: reason})
+}
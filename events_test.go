@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestUpdaterEvents(t *testing.T) {
+	events := make(chan SyncEvent)
+	u := &Updater{
+		Prefix:     "Anime",
+		Statistics: new(Statistics),
+		Events:     events,
+		UpdateTargetBySourceFunc: func(context.Context, TargetID, Source, EntryOptions) error {
+			return nil
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u.Update(context.Background(), []Source{
+			Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching, Progress: 5},
+			Anime{IDAnilist: 2, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching, Progress: 3},
+		}, []Target{
+			Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching, Progress: 3},
+			Anime{IDAnilist: -1, IDMal: 2, Status: StatusWatching, Progress: 3},
+		})
+		close(events)
+	}()
+
+	var kinds []SyncEventKind
+	for e := range events {
+		if e.Prefix != "Anime" {
+			t.Errorf("got prefix %q, want Anime", e.Prefix)
+		}
+		kinds = append(kinds, e.Kind)
+	}
+	<-done
+
+	want := "[started matched updated matched skipped finished]"
+	if got := fmt.Sprint(kinds); got != want {
+		t.Errorf("got events %s, want %s", got, want)
+	}
+}
+
+func TestUpdaterEventsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	u := &Updater{Prefix: "Anime", Statistics: new(Statistics), Events: make(chan SyncEvent)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u.Update(ctx, []Source{Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching}}, nil)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync blocked on undrained events after cancel")
+	}
+}
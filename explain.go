@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// explanation traces how a single source was matched or why it was skipped.
+// Methods are no-op on nil explanation, so it is created only with Explain.
+type explanation struct {
+	title string
+	steps []string
+}
+
+func (e *explanation) addf(format string, v ...any) {
+	if e == nil {
+		return
+	}
+	e.steps = append(e.steps, fmt.Sprintf(format, v...))
+}
+
+func (e *explanation) print(prefix string) {
+	if e == nil {
+		return
+	}
+	log.Printf("[%s] Explain: %s", prefix, e.title)
+	for _, s := range e.steps {
+		log.Printf("[%s]   %s", prefix, s)
+	}
+}
+
+// explainEvent records the event to the trace of the current source.
+// Skipped, updated and error events are final, the trace is printed then.
+func (u *Updater) explainEvent(e SyncEvent) {
+	if u.trace == nil {
+		return
+	}
+
+	switch e.Kind {
+	case SyncEventMatched:
+		u.trace.addf("matched: MAL id %d", e.TargetID)
+		return
+	case SyncEventSkipped:
+		u.trace.addf("skipped: %s", e.Reason)
+	case SyncEventUpdated:
+		u.trace.addf("updated: MAL id %d", e.TargetID)
+	case SyncEventError:
+		u.trace.addf("error: %v", e.Err)
+	default:
+		return
+	}
+
+	u.trace.print(u.Prefix)
+	u.trace = nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Field authorities tell where the synced value of a field comes from.
+const (
+	authorityAnilist = "anilist"
+	authorityMal     = "mal"
+	authorityHighest = "highest"
+	authorityNewest  = "newest" // of the entry which was updated last
+)
+
+// FieldAuthorityConfig sets the authority of every synced field, empty one is AniList.
+type FieldAuthorityConfig struct {
+	Status   string `yaml:"status"`
+	Score    string `yaml:"score"`
+	Progress string `yaml:"progress"`
+	Dates    string `yaml:"dates"`
+}
+
+func (c FieldAuthorityConfig) validate() error {
+	fields := []struct {
+		name, value string
+		numeric     bool
+	}{
+		{"status", c.Status, false},
+		{"score", c.Score, true},
+		{"progress", c.Progress, true},
+		{"dates", c.Dates, false},
+	}
+
+	for _, f := range fields {
+		switch f.value {
+		case "", authorityAnilist, authorityMal, authorityNewest:
+		case authorityHighest:
+			if !f.numeric {
+				return fmt.Errorf("field_authority.%s: %q is only for score and progress", f.name, f.value)
+			}
+		default:
+			return fmt.Errorf("field_authority.%s: unknown authority %q, known: %s, %s, %s, %s",
+				f.name, f.value, authorityAnilist, authorityMal, authorityHighest, authorityNewest)
+		}
+	}
+	return nil
+}
+
+// useTarget reports whether the field value is taken from the target instead of the source.
+// Newest falls back to the source if any update time is unknown.
+func useTarget(authority string, src, tgt float64, srcUpdatedAt, tgtUpdatedAt *time.Time) bool {
+	switch authority {
+	case authorityMal:
+		return true
+	case authorityHighest:
+		return tgt > src
+	case authorityNewest:
+		return srcUpdatedAt != nil && tgtUpdatedAt != nil && tgtUpdatedAt.After(*srcUpdatedAt)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUseTarget(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		authority    string
+		src, tgt     float64
+		srcUpdatedAt *time.Time
+		tgtUpdatedAt *time.Time
+		want         bool
+	}{
+		{"default", "", 5, 7, nil, nil, false},
+		{"anilist", authorityAnilist, 5, 7, nil, nil, false},
+		{"mal", authorityMal, 7, 5, nil, nil, true},
+		{"highest target", authorityHighest, 5, 7, nil, nil, true},
+		{"highest source", authorityHighest, 7, 5, nil, nil, false},
+		{"highest equal", authorityHighest, 7, 7, nil, nil, false},
+		{"newest target", authorityNewest, 0, 0, &older, &newer, true},
+		{"newest source", authorityNewest, 0, 0, &newer, &older, false},
+		{"newest same time", authorityNewest, 0, 0, &older, &older, false},
+		{"newest unknown source time", authorityNewest, 0, 0, nil, &newer, false},
+		{"newest unknown target time", authorityNewest, 0, 0, &older, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := useTarget(tt.authority, tt.src, tt.tgt, tt.srcUpdatedAt, tt.tgtUpdatedAt); got != tt.want {
+				t.Errorf("useTarget() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFieldAuthority(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	started := older.AddDate(0, -1, 0)
+
+	src := Anime{IDMal: 1, Status: StatusWatching, Score: 8, Progress: 5, UpdatedAt: &older}
+	tgt := Anime{IDMal: 1, Status: StatusOnHold, Score: 6, Progress: 7, StartedAt: &started, UpdatedAt: &newer}
+
+	got := src.WithFieldAuthority(tgt, FieldAuthorityConfig{
+		Status:   authorityAnilist,
+		Score:    authorityMal,
+		Progress: authorityHighest,
+		Dates:    authorityNewest,
+	}).(Anime)
+
+	if got.Status != StatusWatching {
+		t.Errorf("status is %s, want AniList %s", got.Status, StatusWatching)
+	}
+	if got.Score != 6 {
+		t.Errorf("score is %v, want MAL 6", got.Score)
+	}
+	if got.Progress != 7 {
+		t.Errorf("progress is %d, want highest 7", got.Progress)
+	}
+	if got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("start date is %v, want newest MAL %v", got.StartedAt, started)
+	}
+
+	m := Manga{Status: MangaStatusReading, Progress: 10}.WithFieldAuthority(
+		Manga{Status: MangaStatusCompleted, Progress: 8}, FieldAuthorityConfig{Status: authorityMal, Progress: authorityHighest}).(Manga)
+	if m.Status != MangaStatusCompleted || m.Progress != 10 {
+		t.Errorf("manga got %s at %d, want MAL status at highest progress 10", m.Status, m.Progress)
+	}
+}
+
+func TestFieldAuthorityConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       FieldAuthorityConfig
+		wantErr bool
+	}{
+		{"empty", FieldAuthorityConfig{}, false},
+		{"all known", FieldAuthorityConfig{Status: authorityMal, Score: authorityHighest, Progress: authorityNewest, Dates: authorityAnilist}, false},
+		{"highest status", FieldAuthorityConfig{Status: authorityHighest}, true},
+		{"highest dates", FieldAuthorityConfig{Dates: authorityHighest}, true},
+		{"unknown", FieldAuthorityConfig{Score: "kitsu"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
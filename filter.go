@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// FiltersConfig limits the sync to source entries matching all set predicates.
+// Empty lists and zero values mean the predicate is not set.
+type FiltersConfig struct {
+	Statuses []string `yaml:"statuses"`
+	Genres   []string `yaml:"genres"`
+	Formats  []string `yaml:"formats"`
+	MinScore float64  `yaml:"min_score"`
+	MaxScore float64  `yaml:"max_score"`
+	MinYear  int      `yaml:"min_year"`
+	MaxYear  int      `yaml:"max_year"`
+
+	ExcludeAdult bool `yaml:"exclude_adult"`
+}
+
+func (f FiltersConfig) matchesFilter(src Source) bool {
+	var (
+		score  float64
+		year   int
+		format string
+		genres []string
+		adult  bool
+	)
+
+	switch v := src.(type) {
+	case Anime:
+		score, year, format, genres, adult = v.Score, v.SeasonYear, v.Format, v.Genres, v.IsAdult
+	case Manga:
+		score, format, genres, adult = v.Score, v.Format, v.Genres, v.IsAdult
+	}
+
+	if f.ExcludeAdult && adult {
+		return false
+	}
+
+	if len(f.Statuses) > 0 && !containsFold(f.Statuses, src.GetStatusString()) {
+		return false
+	}
+
+	if len(f.Formats) > 0 && !containsFold(f.Formats, format) {
+		return false
+	}
+
+	if len(f.Genres) > 0 && !slices.ContainsFunc(genres, func(g string) bool { return containsFold(f.Genres, g) }) {
+		return false
+	}
+
+	if f.MinScore > 0 && score < f.MinScore {
+		return false
+	}
+
+	if f.MaxScore > 0 && score > f.MaxScore {
+		return false
+	}
+
+	if year > 0 { // year is unknown for manga and some anime
+		if f.MinYear > 0 && year < f.MinYear {
+			return false
+		}
+		if f.MaxYear > 0 && year > f.MaxYear {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// matchesTitle reports whether any title of the source equals the given one
+// exactly or after normalization.
+func matchesTitle(src Source, title string) bool {
+	var en string
+	var romaji []string // and native titles
+	switch v := src.(type) {
+	case Anime:
+		en, romaji = v.TitleEN, []string{v.TitleJP, v.TitleRomaji}
+	case Manga:
+		en, romaji = v.TitleEN, []string{v.TitleJP, v.TitleRomaji}
+	default:
+		en = src.GetTitle()
+	}
+
+	if en != "" && (en == title || normalizeTitle(en) == normalizeTitle(title)) {
+		return true
+	}
+	for _, t := range romaji {
+		if t != "" && (t == title || normalizeRomajiTitle(t) == normalizeRomajiTitle(title)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHidden reports whether the source entry is hidden from AniList status lists.
+func isHidden(src Source) bool {
+	switch v := src.(type) {
+	case Anime:
+		return v.HiddenFromStatusLists
+	case Manga:
+		return v.HiddenFromStatusLists
+	default:
+		return false
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+func animeFromAnilist(t *testing.T, data string) Anime {
+	t.Helper()
+	var entry verniy.MediaList
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		t.Fatal(err)
+	}
+	res, err := newAnimeFromMediaListEntry(entry, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestHiddenEntries(t *testing.T) {
+	hidden := animeFromAnilist(t, `{"status": "CURRENT", "progress": 3, "hiddenFromStatusLists": true,
+		"media": {"id": 1, "idMal": 1, "title": {"romaji": "Hidden"}}}`)
+	visible := animeFromAnilist(t, `{"status": "CURRENT", "progress": 3,
+		"media": {"id": 2, "idMal": 2, "title": {"romaji": "Visible"}}}`)
+	if !hidden.HiddenFromStatusLists || visible.HiddenFromStatusLists {
+		t.Fatalf("got hidden %t and %t, want true and false", hidden.HiddenFromStatusLists, visible.HiddenFromStatusLists)
+	}
+
+	tests := []struct {
+		includeHidden bool
+		want          string
+	}{
+		{true, "[1 2]"},
+		{false, "[2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("include hidden ", tt.includeHidden), func(t *testing.T) {
+			var updated []TargetID
+			u := &Updater{
+				Prefix:        "Anime",
+				Statistics:    new(Statistics),
+				IncludeHidden: tt.includeHidden,
+				UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+					updated = append(updated, id)
+					return nil
+				},
+			}
+			u.Update(context.Background(), []Source{hidden, visible}, []Target{
+				Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching},
+				Anime{IDAnilist: -1, IDMal: 2, Status: StatusWatching},
+			})
+
+			if got := fmt.Sprint(updated); got != tt.want {
+				t.Errorf("got updates %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdultEntries(t *testing.T) {
+	adult := animeFromAnilist(t, `{"status": "CURRENT", "media": {"id": 1, "isAdult": true, "title": {"romaji": "Kite", "english": "Kite"}}}`)
+	if !adult.IsAdult {
+		t.Fatal("AniList isAdult isn't kept")
+	}
+
+	var malAdult, malSafe mal.Anime
+	if err := json.Unmarshal([]byte(`{"id": 1, "title": "Kite", "nsfw": "black"}`), &malAdult); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"id": 2, "title": "Kite", "nsfw": "gray"}`), &malSafe); err != nil {
+		t.Fatal(err)
+	}
+	adultTgt, err := newAnimeFromMalAnime(malAdult, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	safeTgt, err := newAnimeFromMalAnime(malSafe, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !adultTgt.IsAdult || safeTgt.IsAdult {
+		t.Fatalf("got MAL adult %t and %t, want true and false", adultTgt.IsAdult, safeTgt.IsAdult)
+	}
+
+	if !adult.SameTypeWithTarget(adultTgt) {
+		t.Error("adult source doesn't match adult target")
+	}
+	if adult.SameTypeWithTarget(safeTgt) {
+		t.Error("adult source matches non-adult target of the same title")
+	}
+
+	f := FiltersConfig{ExcludeAdult: true}
+	if f.matchesFilter(adult) {
+		t.Error("adult source isn't excluded")
+	}
+	if !f.matchesFilter(safeTgt) {
+		t.Error("non-adult source is excluded")
+	}
+}
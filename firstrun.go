@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const firstRunMarkerName = "first_run_done"
+
+// firstRunMarkerPath returns the path of the marker written after the first run,
+// it lives beside the token file.
+func firstRunMarkerPath(tokenFilePath string) string {
+	return filepath.Join(filepath.Dir(tokenFilePath), firstRunMarkerName)
+}
+
+func isFirstRun(markerPath string) bool {
+	_, err := os.Stat(markerPath)
+	return os.IsNotExist(err)
+}
+
+func writeFirstRunMarker(markerPath string) error {
+	if err := createDirIfNotExists(markerPath); err != nil {
+		return err
+	}
+	return os.WriteFile(markerPath, []byte(time.Now().Format(time.RFC3339)+"\n"), 0o600)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+// listFixture is a file with lists as the APIs return them: AniList media list groups
+// or MAL user list entries ({"node": ..., "list_status": ...}), e.g. to reproduce a matching bug
+// without the network or the account.
+type listFixture struct {
+	Anime json.RawMessage `json:"anime"`
+	Manga json.RawMessage `json:"manga"`
+}
+
+// readFixture decodes anime or manga list of the fixture file into v.
+// A missing list is an empty one.
+func readFixture(path, typ string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var f listFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	list := f.Anime
+	if typ == "manga" {
+		list = f.Manga
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(list, v); err != nil {
+		return fmt.Errorf("fixture %s: %s: %w", path, typ, err)
+	}
+	return nil
+}
+
+func (a *App) anilistAnimeList(ctx context.Context) ([]verniy.MediaListGroup, error) {
+	var (
+		groups []verniy.MediaListGroup
+		err    error
+	)
+	if *sourceFile != "" {
+		err = readFixture(*sourceFile, "anime", &groups)
+	} else {
+		var warnings []string
+		groups, warnings, err = a.anilist.GetUserAnimeList(ctx)
+		for _, w := range warnings {
+			a.animeUpdater.Statistics.AddWarning("%s", w)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterAnilistLists(groups, *anilistList), nil
+}
+
+func (a *App) anilistMangaList(ctx context.Context) ([]verniy.MediaListGroup, error) {
+	var (
+		groups []verniy.MediaListGroup
+		err    error
+	)
+	if *sourceFile != "" {
+		err = readFixture(*sourceFile, "manga", &groups)
+	} else {
+		var warnings []string
+		groups, warnings, err = a.anilist.GetUserMangaList(ctx)
+		for _, w := range warnings {
+			a.mangaUpdater.Statistics.AddWarning("%s", w)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterAnilistLists(groups, *anilistList), nil
+}
+
+func (a *App) malAnimeList(ctx context.Context) ([]mal.UserAnime, error) {
+	if *targetFile != "" {
+		var list []mal.UserAnime
+		return list, readFixture(*targetFile, "anime", &list)
+	}
+	return a.mal.GetUserAnimeList(ctx)
+}
+
+func (a *App) malMangaList(ctx context.Context) ([]mal.UserManga, error) {
+	if *targetFile != "" {
+		var list []mal.UserManga
+		return list, readFixture(*targetFile, "manga", &list)
+	}
+	return a.mal.GetUserMangaList(ctx)
+}
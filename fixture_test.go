@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+func writeFixture(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFixture(t *testing.T) {
+	path := writeFixture(t, "mal.json", `{
+		"anime": [{"node": {"id": 52991, "title": "Sousou no Frieren"}, "list_status": {"status": "watching", "num_episodes_watched": 27}}]
+	}`)
+
+	var anime []mal.UserAnime
+	if err := readFixture(path, "anime", &anime); err != nil {
+		t.Fatal(err)
+	}
+	if len(anime) != 1 || anime[0].Anime.ID != 52991 || anime[0].Status.NumEpisodesWatched != 27 {
+		t.Errorf("got %+v", anime)
+	}
+
+	var manga []mal.UserManga
+	if err := readFixture(path, "manga", &manga); err != nil || len(manga) != 0 {
+		t.Errorf("missing manga list: got %v, %v, want empty one", manga, err)
+	}
+
+	if err := readFixture(filepath.Join(t.TempDir(), "missing.json"), "anime", &anime); err == nil {
+		t.Error("no error for missing file")
+	}
+}
+
+func TestSyncFromFixtures(t *testing.T) {
+	src := writeFixture(t, "anilist.json", `{
+		"anime": [{"status": "CURRENT", "entries": [{"status": "CURRENT", "progress": 28, "score": 9,
+			"media": {"id": 154587, "idMal": 52991, "title": {"romaji": "Sousou no Frieren", "english": "Frieren"}, "episodes": 28, "format": "TV"}}]}]
+	}`)
+	tgt := writeFixture(t, "mal.json", `{
+		"anime": [{"node": {"id": 52991, "title": "Sousou no Frieren", "num_episodes": 28}, "list_status": {"status": "watching", "num_episodes_watched": 27}}]
+	}`)
+
+	oldSrc, oldTgt := *sourceFile, *targetFile
+	*sourceFile, *targetFile = src, tgt
+	t.Cleanup(func() { *sourceFile, *targetFile = oldSrc, oldTgt })
+
+	var updated []Anime
+	a := &App{
+		animeUpdater: &Updater{
+			Prefix:     "Anime",
+			Statistics: new(Statistics),
+			UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, _ EntryOptions) error {
+				updated = append(updated, src.(Anime))
+				return nil
+			},
+		},
+		mangaUpdater:  &Updater{Prefix: "Manga", Statistics: new(Statistics)},
+		scoreFormat:   verniy.ScoreFormatPoint10,
+		datesLocation: time.UTC,
+	}
+
+	if err := a.syncAnime(context.Background()); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if len(updated) != 1 || updated[0].IDMal != 52991 || updated[0].Progress != 28 || updated[0].Score != 9 {
+		t.Errorf("got updates %+v, want Frieren at 28 episodes with score 9", updated)
+	}
+}
+
+func TestSyncFromFixturesCapsProgressToAired(t *testing.T) {
+	src := writeFixture(t, "anilist.json", `{
+		"anime": [{"status": "CURRENT", "entries": [{"status": "CURRENT", "progress": 12,
+			"media": {"id": 170942, "idMal": 57334, "title": {"romaji": "Dandadan"}, "episodes": 12, "format": "TV",
+				"status": "RELEASING", "nextAiringEpisode": {"episode": 11}}}]}]
+	}`)
+	tgt := writeFixture(t, "mal.json", `{
+		"anime": [{"node": {"id": 57334, "title": "Dandadan", "num_episodes": 12}, "list_status": {"status": "watching", "num_episodes_watched": 9}}]
+	}`)
+
+	oldSrc, oldTgt := *sourceFile, *targetFile
+	*sourceFile, *targetFile = src, tgt
+	t.Cleanup(func() { *sourceFile, *targetFile = oldSrc, oldTgt })
+
+	var updated []Anime
+	a := &App{
+		config: Config{Sync: SyncConfig{CapProgressToAired: true}},
+		animeUpdater: &Updater{
+			Prefix:     "Anime",
+			Statistics: new(Statistics),
+			UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, _ EntryOptions) error {
+				updated = append(updated, src.(Anime))
+				return nil
+			},
+		},
+		mangaUpdater:  &Updater{Prefix: "Manga", Statistics: new(Statistics)},
+		scoreFormat:   verniy.ScoreFormatPoint10,
+		datesLocation: time.UTC,
+	}
+
+	if err := a.syncAnime(context.Background()); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if len(updated) != 1 || updated[0].Progress != 10 {
+		t.Errorf("got updates %+v, want progress capped to 10 aired episodes", updated)
+	}
+	if w := a.animeUpdater.Statistics.Warnings; len(w) != 1 || !strings.Contains(w[0], "progress capped to 10 aired episodes") {
+		t.Errorf("got warnings %q", w)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formatter writes output of commands like -list and -diff in some format.
+type Formatter interface {
+	Format(w io.Writer, data any) error
+}
+
+// Table is data which can be written by table and CSV formatters,
+// other formatters marshal the data itself.
+type Table interface {
+	Header() []string
+	Rows() [][]string
+}
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatCSV   = "csv"
+)
+
+var formatters = map[string]Formatter{
+	formatTable: tableFormatter{},
+	formatJSON:  jsonFormatter{},
+	formatYAML:  yamlFormatter{},
+	formatCSV:   csvFormatter{},
+}
+
+func formatterNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func newFormatter(name string) (Formatter, error) {
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, known: %s", name, strings.Join(formatterNames(), ", "))
+	}
+	return f, nil
+}
+
+func asTable(data any) (Table, error) {
+	t, ok := data.(Table)
+	if !ok {
+		return nil, fmt.Errorf("%T can't be written as a table", data)
+	}
+	return t, nil
+}
+
+type tableFormatter struct{}
+
+func (tableFormatter) Format(w io.Writer, data any) error {
+	t, err := asTable(data)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.Header(), "\t")))
+	for _, row := range t.Rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Format(w io.Writer, data any) error {
+	t, err := asTable(data)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header()); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, data any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, data any) error {
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestFormatters(t *testing.T) {
+	rows := listRows{
+		{Type: "anime", ID: 52991, Title: "Frieren, \"Beyond Journey's End\"", Status: "watching", Score: 9, Progress: 27, Total: 28},
+		{Type: "manga", ID: 2, Title: "Berserk: The Black Swordsman", Status: "reading", Progress: 120},
+	}
+
+	tests := []struct {
+		format string
+		parse  func(t *testing.T, out []byte)
+	}{
+		{formatJSON, func(t *testing.T, out []byte) {
+			var got listRows
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("got %+v, want %+v", got, rows)
+			}
+		}},
+		{formatYAML, func(t *testing.T, out []byte) {
+			var got listRows
+			if err := yaml.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("got %+v, want %+v", got, rows)
+			}
+		}},
+		{formatCSV, func(t *testing.T, out []byte) {
+			records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append([][]string{rows.Header()}, rows.Rows()...)
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("got %q, want %q", records, want)
+			}
+		}},
+		{formatTable, func(t *testing.T, out []byte) {
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			if len(lines) != 3 || !strings.HasPrefix(lines[0], "TYPE") || !strings.Contains(lines[1], "27/28") {
+				t.Errorf("got table %q", out)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, err := newFormatter(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := f.Format(&buf, rows); err != nil {
+				t.Fatal(err)
+			}
+			tt.parse(t, buf.Bytes())
+		})
+	}
+
+	if _, err := newFormatter("xml"); err == nil {
+		t.Error("no error for unknown format")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+// withGapsOnly keeps every non-empty field of the target, so only the fields
+// missing on MAL are filled from the source and nothing set on MAL is overwritten.
+func withGapsOnly(src Source, tgt Target) Source {
+	switch s := src.(type) {
+	case Anime:
+		t, ok := tgt.(Anime)
+		if !ok {
+			return src
+		}
+		if t.Status != "" && t.Status != StatusUnknown {
+			s.Status = t.Status
+		}
+		if t.Score != 0 {
+			s.Score = t.Score
+		}
+		if t.Progress != 0 {
+			s.Progress = t.Progress
+		}
+		if t.StartedAt != nil {
+			s.StartedAt, s.StartedAtInferred = t.StartedAt, false
+		}
+		if t.FinishedAt != nil {
+			s.FinishedAt, s.FinishedAtInferred = t.FinishedAt, false
+		}
+		return s
+	case Manga:
+		t, ok := tgt.(Manga)
+		if !ok {
+			return src
+		}
+		if t.Status != "" && t.Status != MangaStatusUnknown {
+			s.Status = t.Status
+		}
+		if t.Score != 0 {
+			s.Score = t.Score
+		}
+		if t.Progress != 0 {
+			s.Progress = t.Progress
+		}
+		if t.ProgressVolumes != 0 {
+			s.ProgressVolumes = t.ProgressVolumes
+		}
+		if t.StartedAt != nil {
+			s.StartedAt, s.StartedAtInferred = t.StartedAt, false
+		}
+		if t.FinishedAt != nil {
+			s.FinishedAt, s.FinishedAtInferred = t.FinishedAt, false
+		}
+		return s
+	default:
+		return src
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithGapsOnlyAnime(t *testing.T) {
+	srcStart := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	srcFinish := srcStart.AddDate(0, 3, 0)
+	tgtStart := srcStart.AddDate(0, 0, 1)
+	src := Anime{Status: StatusCompleted, Score: 9, Progress: 12, StartedAt: &srcStart, FinishedAt: &srcFinish}
+
+	tests := []struct {
+		name  string
+		tgt   Anime
+		check func(Anime) bool
+	}{
+		{"empty status is filled", Anime{Status: StatusUnknown}, func(a Anime) bool { return a.Status == StatusCompleted }},
+		{"status is kept", Anime{Status: StatusWatching}, func(a Anime) bool { return a.Status == StatusWatching }},
+		{"empty score is filled", Anime{}, func(a Anime) bool { return a.Score == 9 }},
+		{"score is kept", Anime{Score: 7}, func(a Anime) bool { return a.Score == 7 }},
+		{"empty progress is filled", Anime{}, func(a Anime) bool { return a.Progress == 12 }},
+		{"progress is kept", Anime{Progress: 3}, func(a Anime) bool { return a.Progress == 3 }},
+		{"empty start date is filled", Anime{}, func(a Anime) bool { return a.StartedAt.Equal(srcStart) }},
+		{"start date is kept", Anime{StartedAt: &tgtStart}, func(a Anime) bool { return a.StartedAt.Equal(tgtStart) }},
+		{"empty finish date is filled", Anime{StartedAt: &tgtStart}, func(a Anime) bool { return a.FinishedAt.Equal(srcFinish) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withGapsOnly(src, tt.tgt).(Anime)
+			if !tt.check(got) {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestWithGapsOnlyManga(t *testing.T) {
+	src := Manga{Status: MangaStatusReading, Score: 8, Progress: 50, ProgressVolumes: 5}
+
+	tests := []struct {
+		name  string
+		tgt   Manga
+		check func(Manga) bool
+	}{
+		{"empty status is filled", Manga{Status: MangaStatusUnknown}, func(m Manga) bool { return m.Status == MangaStatusReading }},
+		{"status is kept", Manga{Status: MangaStatusOnHold}, func(m Manga) bool { return m.Status == MangaStatusOnHold }},
+		{"empty score is filled", Manga{}, func(m Manga) bool { return m.Score == 8 }},
+		{"score is kept", Manga{Score: 6}, func(m Manga) bool { return m.Score == 6 }},
+		{"empty chapters are filled", Manga{}, func(m Manga) bool { return m.Progress == 50 }},
+		{"chapters are kept", Manga{Progress: 40}, func(m Manga) bool { return m.Progress == 40 }},
+		{"empty volumes are filled", Manga{Progress: 40}, func(m Manga) bool { return m.ProgressVolumes == 5 }},
+		{"volumes are kept", Manga{ProgressVolumes: 4}, func(m Manga) bool { return m.ProgressVolumes == 4 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withGapsOnly(src, tt.tgt).(Manga)
+			if !tt.check(got) {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ require (
 require (
 	github.com/rl404/verniy v0.3.1
 	golang.org/x/exp v0.0.0-20241108190413-2d47ceb2692f
+	golang.org/x/sync v0.9.0
+	golang.org/x/text v0.20.0
 )
 
 require github.com/nstratos/go-myanimelist v0.9.5
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const defaultHookTimeout = time.Minute
+
+type HooksConfig struct {
+	PostSync    []string      `yaml:"post_sync"` // command and its args
+	Timeout     time.Duration `yaml:"timeout"`
+	FailOnError bool          `yaml:"fail_on_error"`
+}
+
+func (c HooksConfig) validate() error {
+	if c.Timeout <= 0 {
+		return errors.New("hooks.timeout must be positive")
+	}
+	return nil
+}
+
+// SyncSummary is passed to the post sync hook as JSON on stdin.
+type SyncSummary struct {
+	DryRun   bool             `json:"dry_run"`
+	Updated  int              `json:"updated"`
+	Skipped  int              `json:"skipped"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Anime    TypeSummary      `json:"anime"`
+	Manga    TypeSummary      `json:"manga"`
+	Items    []StatisticsItem `json:"items"`
+}
+
+// TypeSummary is the summary of anime or manga sync.
+type TypeSummary struct {
+	Total    int            `json:"total"`
+	Updated  int            `json:"updated"`
+	Skipped  int            `json:"skipped"`
+	Errors   map[string]int `json:"errors"` // by category
+	Warnings []string       `json:"warnings"`
+}
+
+func newTypeSummary(s *Statistics) TypeSummary {
+	errs := make(map[string]int, len(s.Errors))
+	for c, n := range s.Errors {
+		errs[string(c)] = n
+	}
+	return TypeSummary{
+		Total:    s.TotalCount,
+		Updated:  s.UpdatedCount,
+		Skipped:  s.SkippedCount,
+		Errors:   errs,
+		Warnings: s.Warnings,
+	}
+}
+
+func newSyncSummary(anime, manga *Statistics) SyncSummary {
+	sum := SyncSummary{
+		DryRun: *dryRun,
+		Anime:  newTypeSummary(anime),
+		Manga:  newTypeSummary(manga),
+	}
+	for _, s := range []*Statistics{manga, anime} {
+		sum.Updated += s.UpdatedCount
+		sum.Skipped += s.SkippedCount
+		sum.Warnings += len(s.Warnings)
+		for _, n := range s.Errors {
+			sum.Errors += n
+		}
+		sum.Items = append(sum.Items, s.Items...)
+	}
+	return sum
+}
+
+// runPostSyncHook runs the hook command with the summary JSON on stdin and
+// the counts in SYNC_UPDATED, SYNC_SKIPPED, SYNC_ERRORS and SYNC_WARNINGS env variables.
+// The command output is written to the log.
+func runPostSyncHook(ctx context.Context, c HooksConfig, sum SyncSummary) error {
+	if len(c.PostSync) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(sum)
+	if err != nil {
+		return fmt.Errorf("marshal summary: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.PostSync[0], c.PostSync[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"SYNC_UPDATED="+strconv.Itoa(sum.Updated),
+		"SYNC_SKIPPED="+strconv.Itoa(sum.Skipped),
+		"SYNC_ERRORS="+strconv.Itoa(sum.Errors),
+		"SYNC_WARNINGS="+strconv.Itoa(sum.Warnings),
+		"SYNC_DRY_RUN="+strconv.FormatBool(sum.DryRun),
+	)
+
+	out, err := cmd.CombinedOutput()
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		log.Printf("[Hook] %s", s.Text())
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("post sync hook timed out after %s", c.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("post sync hook: %w", err)
+	}
+	return nil
+}
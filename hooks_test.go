@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPostSyncHook(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the hook")
+	}
+
+	var out strings.Builder
+	w, flags := log.Writer(), log.Flags()
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	})
+
+	anime := &Statistics{UpdatedCount: 2, SkippedCount: 1, TotalCount: 3}
+	manga := &Statistics{UpdatedCount: 1, TotalCount: 1}
+	c := HooksConfig{
+		PostSync: []string{"sh", "-c", `echo "updated $SYNC_UPDATED skipped $SYNC_SKIPPED"; cat`},
+		Timeout:  defaultHookTimeout,
+	}
+
+	if err := runPostSyncHook(context.Background(), c, newSyncSummary(anime, manga)); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"[Hook] updated 3 skipped 1", `"updated":3`, `"anime":{"total":3`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log has no %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunPostSyncHookFails(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the hook")
+	}
+
+	sum := newSyncSummary(new(Statistics), new(Statistics))
+	if err := runPostSyncHook(context.Background(), HooksConfig{PostSync: []string{"sh", "-c", "exit 3"}, Timeout: time.Minute}, sum); err == nil {
+		t.Error("no error on non-zero exit")
+	}
+
+	err := runPostSyncHook(context.Background(), HooksConfig{PostSync: []string{"sh", "-c", "exec sleep 5"}, Timeout: 50 * time.Millisecond}, sum)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got error %v, want timeout", err)
+	}
+
+	if err := runPostSyncHook(context.Background(), HooksConfig{Timeout: time.Minute}, sum); err != nil {
+		t.Errorf("no hook: got error %v", err)
+	}
+}
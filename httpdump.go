@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// maxDumpBodySize is the size of request and response bodies kept in the dump.
+const maxDumpBodySize = 4 << 10
+
+const redactedValue = "***"
+
+// dumpRedactedHeaders carry credentials or session data.
+var dumpRedactedHeaders = map[string]struct{}{
+	"Authorization": {},
+	"Cookie":        {},
+	"Set-Cookie":    {},
+}
+
+// dumpRedactedParams are redacted in query strings, form and JSON bodies.
+var dumpRedactedParams = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"client_secret",
+	"client_id",
+	"code",
+	"code_verifier",
+	"password",
+	"username",
+	"userName",
+	"name",
+}
+
+var (
+	dumpJSONParamRegexp = regexp.MustCompile(`("(?:` + strings.Join(dumpRedactedParams, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	dumpUserPathRegexp  = regexp.MustCompile(`(/users/)[^/@][^/]*`) // MAL user name, @me is kept
+	dumpFormParamRegexp = regexp.MustCompile(`(^|[&?])((?:` + strings.Join(dumpRedactedParams, "|") + `)=)[^&]*`)
+)
+
+// dumpTransport writes every HTTP exchange to w with credentials and user names redacted,
+// so the dump can be attached to a bug report.
+type dumpTransport struct {
+	base http.RoundTripper
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// withHTTPDump makes OAuth and API clients created with the context dump HTTP exchanges to w.
+// The dump is below the OAuth transport, so Authorization header is in it and is redacted.
+func withHTTPDump(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: &dumpTransport{base: http.DefaultTransport, w: w},
+	})
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "> %s %s\n", req.Method, redactURL(req.URL))
+	writeDumpHeaders(&sb, ">", req.Header)
+	writeDumpBody(&sb, ">", reqBody)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&sb, "< error after %s: %v\n", time.Since(start).Round(time.Millisecond), err)
+		t.write(sb.String())
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	fmt.Fprintf(&sb, "< %s in %s\n", resp.Status, time.Since(start).Round(time.Millisecond))
+	writeDumpHeaders(&sb, "<", resp.Header)
+	writeDumpBody(&sb, "<", respBody)
+	if err != nil {
+		fmt.Fprintf(&sb, "< error reading body: %v\n", err)
+		t.write(sb.String())
+		return nil, err
+	}
+	t.write(sb.String())
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+func (t *dumpTransport) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// The dump is best effort, it must not break the sync.
+	_, _ = io.WriteString(t.w, s)
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.RawQuery = redactParams(c.RawQuery)
+	return dumpUserPathRegexp.ReplaceAllString(c.String(), "${1}"+redactedValue)
+}
+
+func redactParams(s string) string {
+	return dumpFormParamRegexp.ReplaceAllString(s, "${1}${2}"+redactedValue)
+}
+
+func writeDumpHeaders(sb *strings.Builder, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if _, ok := dumpRedactedHeaders[http.CanonicalHeaderKey(k)]; ok {
+			v = redactedValue
+		}
+		fmt.Fprintf(sb, "%s %s: %s\n", prefix, k, v)
+	}
+}
+
+func writeDumpBody(sb *strings.Builder, prefix string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	// Redacted before truncation, so a cut value can't escape the patterns.
+	s := dumpJSONParamRegexp.ReplaceAllString(string(body), "${1}\""+redactedValue+"\"")
+	s = redactParams(s)
+
+	var suffix string
+	if len(s) > maxDumpBodySize {
+		suffix = fmt.Sprintf("... (%d bytes truncated)", len(s)-maxDumpBodySize)
+		s = s[:maxDumpBodySize]
+	}
+
+	fmt.Fprintf(sb, "%s\n%s%s\n", prefix, s, suffix)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestDumpTransportRedacts(t *testing.T) {
+	var dump strings.Builder
+	tr := &dumpTransport{
+		base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Set-Cookie": {"session=s3cret"}},
+				Body:       io.NopCloser(strings.NewReader(`{"access_token":"t0ken","data":{"id":1}}`)),
+			}, nil
+		}),
+		w: &dump,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/someone/animelist?limit=100", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer t0ken")
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "t0ken") {
+		t.Errorf("response body was changed: %s", body)
+	}
+
+	s := dump.String()
+	for _, want := range []string{"https://api.myanimelist.net/v2/users/***/animelist?limit=100", "200 OK", `"id":1`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("dump has no %q:\n%s", want, s)
+		}
+	}
+	for _, secret := range []string{"t0ken", "s3cret", "someone"} {
+		if strings.Contains(s, secret) {
+			t.Errorf("dump has %q:\n%s", secret, s)
+		}
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestDumpTransportBodyError(t *testing.T) {
+	errReset := errors.New("connection reset")
+	var closed bool
+	tr := &dumpTransport{
+		base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Body: struct {
+					io.Reader
+					io.Closer
+				}{errReader{errReset}, closerFunc(func() error { closed = true; return nil })},
+			}, nil
+		}),
+		w: io.Discard,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://graphql.anilist.co", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := tr.RoundTrip(req)
+	if !errors.Is(err, errReset) {
+		t.Errorf("got error %v, want %v", err, errReset)
+	}
+	if resp != nil {
+		t.Error("got response with error")
+	}
+	if !closed {
+		t.Error("body isn't closed")
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
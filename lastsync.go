@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const lastSyncFileName = "last_sync"
+
+// lastSyncPath returns the path of the file with the time of the last successful sync,
+// it lives beside the token file.
+func lastSyncPath(tokenFilePath string) string {
+	return filepath.Join(filepath.Dir(tokenFilePath), lastSyncFileName)
+}
+
+// readLastSync returns false if there was no successful sync yet or the file is broken.
+func readLastSync(path string) (time.Time, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func writeLastSync(path string, t time.Time) error {
+	if err := createDirIfNotExists(path); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(t.Format(time.RFC3339)+"\n"), 0o600)
+}
+
+// advanceLastSync records the start of the run as the time of the last successful sync.
+// Failed entries must be retried next time, so the time isn't advanced then, nor on dry run.
+// Entries skipped as too recent must be retried too, so the time is moved back by their age.
+func advanceLastSync(path string, startedAt time.Time, minEntryAge time.Duration, failed bool) error {
+	if *dryRun || failed {
+		return nil
+	}
+	return writeLastSync(path, startedAt.Add(-minEntryAge))
+}
+
+// sourceUpdatedAt returns the time the source entry was updated, nil if unknown.
+func sourceUpdatedAt(src Source) *time.Time {
+	switch v := src.(type) {
+	case Anime:
+		return v.UpdatedAt
+	case Manga:
+		return v.UpdatedAt
+	default:
+		return nil
+	}
+}
+
+// updatedBefore reports whether the source is known to be updated before t.
+// Sources without update time are never skipped.
+func updatedBefore(src Source, t time.Time) bool {
+	updatedAt := sourceUpdatedAt(src)
+	return updatedAt != nil && updatedAt.Before(t)
+}
+
+// updatedAfter reports whether the source is known to be updated after t.
+func updatedAfter(src Source, t time.Time) bool {
+	updatedAt := sourceUpdatedAt(src)
+	return updatedAt != nil && updatedAt.After(t)
+}
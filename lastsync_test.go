@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinceLastRunFirstRun(t *testing.T) {
+	path := lastSyncPath(filepath.Join(t.TempDir(), "token.json"))
+	if _, ok := readLastSync(path); ok {
+		t.Fatal("first run has the last sync time")
+	}
+
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := advanceLastSync(path, startedAt, time.Minute, false); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := readLastSync(path)
+	if !ok || !got.Equal(startedAt.Add(-time.Minute)) {
+		t.Errorf("got last sync %v, %t, want %v", got, ok, startedAt.Add(-time.Minute))
+	}
+}
+
+func TestSinceLastRunIncremental(t *testing.T) {
+	lastSync := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before, after := lastSync.Add(-time.Hour), lastSync.Add(time.Hour)
+
+	var updated []TargetID
+	u := &Updater{
+		Prefix:     "Anime",
+		Statistics: new(Statistics),
+		Since:      lastSync,
+		UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+			updated = append(updated, id)
+			return nil
+		},
+	}
+	u.Update(context.Background(), []Source{
+		Anime{IDAnilist: 1, IDMal: 1, Status: StatusWatching, Progress: 2, UpdatedAt: &before},
+		Anime{IDAnilist: 2, IDMal: 2, Status: StatusWatching, Progress: 2, UpdatedAt: &after},
+		Anime{IDAnilist: 3, IDMal: 3, Status: StatusWatching, Progress: 2},
+	}, []Target{
+		Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching, Progress: 1},
+		Anime{IDAnilist: -1, IDMal: 2, Status: StatusWatching, Progress: 1},
+		Anime{IDAnilist: -1, IDMal: 3, Status: StatusWatching, Progress: 1},
+	})
+
+	if len(updated) != 2 || updated[0] != 2 || updated[1] != 3 {
+		t.Errorf("got updated %v, want [2 3]: updated since the last run or unknown", updated)
+	}
+	if u.Statistics.SkippedCount != 1 {
+		t.Errorf("got %d skipped, want 1", u.Statistics.SkippedCount)
+	}
+}
+
+func TestSinceLastRunFailedRun(t *testing.T) {
+	path := lastSyncPath(filepath.Join(t.TempDir(), "token.json"))
+	lastSync := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := writeLastSync(path, lastSync); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := advanceLastSync(path, lastSync.Add(time.Hour), 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readLastSync(path); !got.Equal(lastSync) {
+		t.Errorf("failed run advanced the last sync to %v", got)
+	}
+
+	*dryRun = true
+	t.Cleanup(func() { *dryRun = false })
+	if err := advanceLastSync(path, lastSync.Add(time.Hour), 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readLastSync(path); !got.Equal(lastSync) {
+		t.Errorf("dry run advanced the last sync to %v", got)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	listServiceAnilist = "anilist"
+	listServiceMal     = "mal"
+)
+
+func validateListService(s string) error {
+	switch s {
+	case "", listServiceAnilist, listServiceMal:
+		return nil
+	default:
+		return fmt.Errorf("-list: unknown service %q, use %q or %q", s, listServiceAnilist, listServiceMal)
+	}
+}
+
+// listRow is an entry of AniList or MAL list.
+type listRow struct {
+	Type     string  `json:"type" yaml:"type"`
+	ID       int     `json:"id" yaml:"id"`
+	Title    string  `json:"title" yaml:"title"`
+	Status   string  `json:"status" yaml:"status"`
+	Score    float64 `json:"score" yaml:"score"`
+	Progress int     `json:"progress" yaml:"progress"`
+	Total    int     `json:"total" yaml:"total"` // episodes or chapters, 0 if unknown
+}
+
+type listRows []listRow
+
+func (listRows) Header() []string {
+	return []string{"type", "id", "title", "status", "score", "progress"}
+}
+
+func (rows listRows) Rows() [][]string {
+	res := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, []string{
+			r.Type,
+			strconv.Itoa(r.ID),
+			r.Title,
+			r.Status,
+			fmt.Sprintf("%.0f", r.Score),
+			fmt.Sprintf("%d/%d", r.Progress, r.Total),
+		})
+	}
+	return res
+}
+
+// List prints the list of the service as the sync sees it, nothing is updated.
+// AniList entries are printed after status mapping and score normalization.
+func (a *App) List(ctx context.Context, service string, f Formatter, w io.Writer) error {
+	var rows listRows
+
+	if *mangaSync || *allSync {
+		mangaRows, err := a.listManga(ctx, service)
+		if err != nil {
+			return fmt.Errorf("error listing manga: %w", err)
+		}
+		rows = append(rows, mangaRows...)
+	}
+
+	if !(*mangaSync) || *allSync {
+		animeRows, err := a.listAnime(ctx, service)
+		if err != nil {
+			return fmt.Errorf("error listing anime: %w", err)
+		}
+		rows = append(rows, animeRows...)
+	}
+
+	return f.Format(w, rows)
+}
+
+func (a *App) listAnime(ctx context.Context, service string) (listRows, error) {
+	var (
+		animes []Anime
+		err    error
+	)
+	if service == listServiceAnilist {
+		animes, err = a.anilistAnimes(ctx)
+	} else {
+		animes, err = a.malAnimes(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(listRows, 0, len(animes))
+	for _, ani := range animes {
+		id := ani.IDMal
+		if service == listServiceAnilist {
+			id = ani.IDAnilist
+		}
+		rows = append(rows, listRow{
+			Type:     "anime",
+			ID:       id,
+			Title:    displayTitle(ani, a.config.Display.TitleSource),
+			Status:   string(ani.Status),
+			Score:    ani.Score,
+			Progress: ani.Progress,
+			Total:    ani.NumEpisodes,
+		})
+	}
+	return rows, nil
+}
+
+func (a *App) listManga(ctx context.Context, service string) (listRows, error) {
+	var (
+		mangas []Manga
+		err    error
+	)
+	if service == listServiceAnilist {
+		mangas, err = a.anilistMangas(ctx)
+	} else {
+		mangas, err = a.malMangas(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(listRows, 0, len(mangas))
+	for _, m := range mangas {
+		id := m.IDMal
+		if service == listServiceAnilist {
+			id = m.IDAnilist
+		}
+		rows = append(rows, listRow{
+			Type:     "manga",
+			ID:       id,
+			Title:    displayTitle(m, a.config.Display.TitleSource),
+			Status:   string(m.Status),
+			Score:    m.Score,
+			Progress: m.Progress,
+			Total:    m.Chapters,
+		})
+	}
+	return rows, nil
+}
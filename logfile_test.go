@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.log")
+	r, err := newRotatingFile(path, 16, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	for _, line := range []string{"first run line\n", "second run\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("old file isn't kept: %v", err)
+	}
+	if string(old) != "first run line\n" {
+		t.Errorf("old file has %q", old)
+	}
+
+	cur, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cur) != "second run\n" {
+		t.Errorf("new file has %q", cur)
+	}
+}
+
+func TestRotatingFileKeepsMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.log")
+	r, err := newRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got files %s, want the current one and 2 old ones", strings.Join(files, ", "))
+	}
+	if old, _ := os.ReadFile(path + ".2"); string(old) != "2\n" {
+		t.Errorf("oldest kept file has %q, want the second line", old)
+	}
+}
@@ -2,24 +2,96 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 var (
 	configFile = flag.String("c", "config.yaml", "path to config file")
 	forceSync  = flag.Bool("f", false, "force sync all animes")
 	dryRun     = flag.Bool("d", false, "dry run without updating MyAnimeList")
+	animeSync  = flag.Bool("anime", false, "sync anime only (default)")
 	mangaSync  = flag.Bool("manga", false, "sync manga instead of anime")
 	allSync    = flag.Bool("all", false, "sync all animes and mangas")
 	verbose    = flag.Bool("verbose", false, "enable verbose logging")
+
+	onlyStatusChanges = flag.Bool("only-status-changes", false, "sync only status changes, ignore score, progress and dates")
+	yearTolerance     = flag.Int("year-tolerance", 1, "max difference in season years for title matches, negative to disable")
+	verify            = flag.Bool("verify", false, "re-fetch updated entries after sync and report the ones that didn't change")
+	noBrowser         = flag.Bool("no-browser", false, "do not open authorization URL in browser")
+	printConfig       = flag.Bool("print-config", false, "print effective config with redacted secrets and exit")
+	noFuzzyTitle      = flag.Bool("no-fuzzy-title", false, "disable matching by title search, entries without MAL ID are reported as unmatched")
+	batchSize         = flag.Int("batch-size", 0, "number of entries per AniList list request, 0 to fetch the whole list at once")
+	failOnWarnings    = flag.Bool("fail-on-warnings", false, "exit with code 2 if any warnings were recorded")
+	startSeason       = flag.String("start-season", "", "sync only anime aired since the season, e.g. 2024-summer")
+	endSeason         = flag.String("end-season", "", "sync only anime aired until the season, e.g. 2024-fall")
+	iUnderstand       = flag.Bool("i-understand", false, "allow updates on the first run without a dry run")
+	onlyNew           = flag.Bool("only-new", false, "only add entries missing on MAL, never update existing ones")
+	onlyTitle         = flag.String("only-title", "", "sync only the entry with the given title")
+	listService       = flag.String("list", "", "print anilist or mal list without syncing and exit")
+	restoreFile       = flag.String("restore", "", "restore MAL list from the backup file and exit")
+	sinceLastRun      = flag.Bool("since-last-run", false, "sync only entries updated on AniList since the last successful run")
+	summaryCSV        = flag.String("summary-csv", "", "write a CSV row per processed entry to the file")
+	summaryJSON       = flag.String("summary-json", "", "write the summary with all warnings, errors and skipped entries to the JSON file")
+	diffFormat        = flag.String("diff", "", "print differences between AniList and MAL lists as text, csv, json or yaml and exit")
+	explain           = flag.Bool("explain", false, "print how each entry was matched or why it was skipped")
+	outputFormat      = flag.String("format", formatTable, "output format of -list and -check-mappings: table, csv, json or yaml")
+	checkNotesMapping = flag.Bool("check-mappings", false, "print redundant and dangling mal:<id> mappings in AniList notes and exit")
+	pruneNotesMapping = flag.Bool("prune-mappings", false, "remove redundant and dangling mal:<id> mappings from AniList notes and exit, -d only prints them")
+	dumpHTTP          = flag.String("dump-http", "", "append HTTP requests and responses with redacted secrets to the file for bug reports")
+	sourceFile        = flag.String("source-file", "", "read AniList lists from the JSON file instead of AniList API")
+	targetFile        = flag.String("target-file", "", "read MAL lists from the JSON file instead of MAL API")
+	anilistList       = flag.String("anilist-list", "", "sync only the AniList list with the name, e.g. Watching or a custom list")
+	randomizeOrder    = flag.Bool("randomize-order", false, "sync entries in random order, so the same entries don't fail first on every run")
+	randomizeSeed     = flag.Int64("randomize-seed", 0, "seed of -randomize-order, 0 is random")
+	strictIDOnly      = flag.Bool("strict-id-only", false, "match only by MAL ID from AniList or notes, report other entries as unmatched")
+
+	repeatingAsCompleted  = flag.Bool("include-repeating-as-completed", false, "sync rewatching and rereading entries as completed")
+	allowUsernameMismatch = flag.Bool("allow-username-mismatch", false, "don't check that tokens belong to the configured users")
+)
+
+// Exit codes are distinct from the code 1 of fatal errors.
+const (
+	exitCodeWarnings       = 2
+	exitCodePartialFailure = 3 // one of anime and manga syncs failed with -all
 )
 
 func main() {
 	flag.Parse()
 
+	if *animeSync && *mangaSync && !(*allSync) {
+		log.Fatalf("error: -anime and -manga can't be used together, use -all to sync both")
+	}
+
+	if err := validateListService(*listService); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	if err := validateBatchSize(*batchSize); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	listOutput, err := newFormatter(*outputFormat)
+	if err != nil {
+		log.Fatalf("error: -format: %v", err)
+	}
+
+	var diffOutput Formatter
+	if *diffFormat != "" {
+		if diffOutput, err = diffFormatter(*diffFormat); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -28,12 +100,166 @@ func main() {
 		log.Fatalf("error: %v", err)
 	}
 
+	if config.Log.File != "" {
+		f, err := newRotatingFile(config.Log.File, int64(config.Log.MaxSizeMB)<<20, config.Log.MaxFiles)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		defer f.Close()
+
+		if config.Log.FileOnly {
+			log.SetOutput(f)
+		} else {
+			log.SetOutput(io.MultiWriter(os.Stderr, f))
+		}
+	}
+
+	if *dumpHTTP != "" {
+		f, err := os.OpenFile(*dumpHTTP, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			log.Fatalf("error: -dump-http: %v", err)
+		}
+		defer f.Close()
+		ctx = withHTTPDump(ctx, f)
+	}
+
+	if *printConfig {
+		data, err := yaml.Marshal(config.redacted())
+		if err != nil {
+			log.Fatalf("marshal config: %v", err)
+		}
+		fmt.Print(string(data))
+		return
+	}
+
+	if *listService != "" {
+		app, err := NewApp(ctx, config)
+		if err != nil {
+			log.Fatalf("create app: %v", err)
+		}
+		defer app.Close()
+
+		if err := app.List(ctx, *listService, listOutput, os.Stdout); err != nil {
+			log.Fatalf("list: %v", err)
+		}
+		return
+	}
+
+	if *diffFormat != "" {
+		app, err := NewApp(ctx, config)
+		if err != nil {
+			log.Fatalf("create app: %v", err)
+		}
+		defer app.Close()
+
+		if err := app.Diff(ctx, diffOutput, os.Stdout); err != nil {
+			log.Fatalf("diff: %v", err)
+		}
+		return
+	}
+
+	if *checkNotesMapping || *pruneNotesMapping {
+		app, err := NewApp(ctx, config)
+		if err != nil {
+			log.Fatalf("create app: %v", err)
+		}
+		defer app.Close()
+
+		if err := app.CheckMappings(ctx, listOutput, os.Stdout); err != nil {
+			log.Fatalf("check mappings: %v", err)
+		}
+		return
+	}
+
+	if *restoreFile != "" {
+		app, err := NewApp(ctx, config)
+		if err != nil {
+			log.Fatalf("create app: %v", err)
+		}
+		defer app.Close()
+
+		if err := app.Restore(ctx, *restoreFile); err != nil {
+			log.Fatalf("restore: %v", err)
+		}
+		return
+	}
+
+	markerPath := firstRunMarkerPath(config.TokenFilePath)
+	firstRun := isFirstRun(markerPath)
+	if firstRun && !(*dryRun) && !(*iUnderstand) {
+		log.Println("======================================================================")
+		log.Println("First run: no updates will be made, running in dry run mode.")
+		log.Println("Review the changes below and run again to apply them,")
+		log.Println("or use -i-understand to apply them on the first run.")
+		log.Println("Use -strict-id-only to skip entries which can be matched by title only.")
+		log.Println("======================================================================")
+		*dryRun = true
+	}
+
 	app, err := NewApp(ctx, config)
 	if err != nil {
 		log.Fatalf("create app: %v", err)
 	}
+	defer app.Close()
 
-	if err := app.Run(ctx); err != nil {
+	lastSyncFile := lastSyncPath(config.TokenFilePath)
+	startedAt := time.Now()
+	if *sinceLastRun {
+		if t, ok := readLastSync(lastSyncFile); ok {
+			log.Printf("Syncing entries updated since the last run at %s", t.Local().Format(time.DateTime))
+			app.SetSince(t)
+		} else {
+			log.Println("Warning: no successful run recorded yet, syncing all entries")
+		}
+	}
+
+	var partialErr *PartialSyncError
+	err = app.Run(ctx)
+	if err != nil && !errors.As(err, &partialErr) {
 		log.Fatalf("run app: %v", err)
 	}
+
+	if *sinceLastRun {
+		if err := advanceLastSync(lastSyncFile, startedAt, config.Sync.MinEntryAge, app.HasErrors()); err != nil {
+			log.Printf("Error writing last sync time: %v", err)
+		}
+	}
+
+	if *summaryCSV != "" {
+		if err := app.WriteSummaryCSV(*summaryCSV); err != nil {
+			log.Printf("Error writing summary CSV: %v", err)
+		}
+	}
+
+	if *summaryJSON != "" {
+		if err := app.WriteSummaryJSON(*summaryJSON); err != nil {
+			log.Printf("Error writing summary JSON: %v", err)
+		}
+	}
+
+	if firstRun {
+		if err := writeFirstRunMarker(markerPath); err != nil {
+			log.Printf("Error writing first run marker: %v", err)
+		}
+	}
+
+	if err := app.RunPostSyncHook(ctx); err != nil {
+		if config.Hooks.FailOnError {
+			app.Close()
+			log.Fatalf("run hook: %v", err)
+		}
+		log.Printf("Warning: %v", err)
+	}
+
+	if partialErr != nil {
+		log.Printf("Sync partially failed, exiting with code %d: %v", exitCodePartialFailure, partialErr)
+		app.Close()
+		os.Exit(exitCodePartialFailure)
+	}
+
+	if *failOnWarnings && app.HasWarnings() {
+		log.Printf("Warnings were recorded, exiting with code %d", exitCodeWarnings)
+		app.Close()
+		os.Exit(exitCodeWarnings)
+	}
 }
@@ -53,6 +53,26 @@ type Manga struct {
 	Volumes         int
 	StartedAt       *time.Time
 	FinishedAt      *time.Time
+	Format          string
+	Genres          []string
+	IsAdult         bool
+	Rereading       bool
+	Repeat          int
+	UpdatedAt       *time.Time
+	CreatedAt       *time.Time
+
+	StartedAtInferred  bool
+	FinishedAtInferred bool
+
+	HiddenFromStatusLists bool
+	AnilistStatus         verniy.MediaListStatus // source status before mapping to MAL one
+	AnilistScore          float64                // source score before normalization for MAL
+	NotesIDMal            int                    // from "mal:<id>" token in AniList notes
+	CommentsIDAnilist     int                    // from "anilist:<id>" token in MAL comments
+
+	// MAL only fields, nil for AniList entries, so they are never cleared by the sync.
+	Priority    *int
+	RereadValue *int
 }
 
 func (m Manga) GetTargetID() TargetID {
@@ -63,7 +83,7 @@ func (m Manga) GetStatusString() string {
 	return string(m.Status)
 }
 
-func (m Manga) GetStringDiffWithTarget(t Target) string {
+func (m Manga) GetStringDiffWithTarget(t Target, o EntryOptions) string {
 	b, ok := t.(Manga)
 	if !ok {
 		return "Diff{undefined}"
@@ -74,7 +94,7 @@ func (m Manga) GetStringDiffWithTarget(t Target) string {
 	if m.Status != b.Status {
 		sb.WriteString(fmt.Sprintf("Status: %s -> %s, ", m.Status, b.Status))
 	}
-	if m.Score != b.Score {
+	if o.syncsScore(m.Score) && m.Score != b.Score {
 		sb.WriteString(fmt.Sprintf("Score: %f -> %f, ", m.Score, b.Score))
 	}
 	if m.Progress != b.Progress {
@@ -83,11 +103,23 @@ func (m Manga) GetStringDiffWithTarget(t Target) string {
 	if m.ProgressVolumes != b.ProgressVolumes {
 		sb.WriteString(fmt.Sprintf("ProgressVolumes: %d -> %d, ", m.ProgressVolumes, b.ProgressVolumes))
 	}
+	if m.Rereading != b.Rereading {
+		sb.WriteString(fmt.Sprintf("Rereading: %t -> %t, ", m.Rereading, b.Rereading))
+	}
+	if m.Repeat != b.Repeat {
+		sb.WriteString(fmt.Sprintf("Repeat: %d -> %d, ", m.Repeat, b.Repeat))
+	}
+	if !sameDates(m.StartedAt, b.StartedAt, o.location()) && !(o.DatesNoClear && m.StartedAt == nil) && m.syncsDates(o) {
+		sb.WriteString(fmt.Sprintf("StartedAt: %s -> %s, ", formatDate(m.StartedAt, m.StartedAtInferred), formatDate(b.StartedAt, false)))
+	}
+	if !sameDates(m.FinishedAt, b.FinishedAt, o.location()) && !(o.DatesNoClear && m.FinishedAt == nil) && m.syncsDates(o) {
+		sb.WriteString(fmt.Sprintf("FinishedAt: %s -> %s, ", formatDate(m.FinishedAt, m.FinishedAtInferred), formatDate(b.FinishedAt, false)))
+	}
 	sb.WriteString("}")
 	return sb.String()
 }
 
-func (m Manga) SameProgressWithTarget(t Target) bool {
+func (m Manga) SameProgressWithTarget(t Target, o EntryOptions) bool {
 	b, ok := t.(Manga)
 	if !ok {
 		return false
@@ -97,22 +129,43 @@ func (m Manga) SameProgressWithTarget(t Target) bool {
 		DPrintf("Status: %s != %s", m.Status, b.Status)
 		return false
 	}
-	if m.Score != b.Score {
+	if o.syncsScore(m.Score) && m.Score != b.Score {
 		DPrintf("Score: %f != %f", m.Score, b.Score)
 		return false
 	}
-	if m.Progress != b.Progress {
-		DPrintf("Progress: %d != %d", m.Progress, b.Progress)
+	if !sameMangaProgress(m.Progress, m.Chapters, b.Progress, b.Chapters) {
+		DPrintf("Progress: %d/%d != %d/%d", m.Progress, m.Chapters, b.Progress, b.Chapters)
 		return false
 	}
-	if m.ProgressVolumes != b.ProgressVolumes {
-		DPrintf("ProgressVolumes: %d != %d", m.ProgressVolumes, b.ProgressVolumes)
+	if !sameMangaProgress(m.ProgressVolumes, m.Volumes, b.ProgressVolumes, b.Volumes) {
+		DPrintf("ProgressVolumes: %d/%d != %d/%d", m.ProgressVolumes, m.Volumes, b.ProgressVolumes, b.Volumes)
+		return false
+	}
+	if m.Rereading != b.Rereading {
+		DPrintf("Rereading: %t != %t", m.Rereading, b.Rereading)
+		return false
+	}
+	if m.Repeat != b.Repeat {
+		DPrintf("Repeat: %d != %d", m.Repeat, b.Repeat)
 		return false
 	}
 
 	return true
 }
 
+// sameMangaProgress compares progress the same way as Anime.SameProgressWithTarget does.
+// Ongoing series have 0 total chapters or volumes, then progress is compared as is,
+// otherwise the number of remaining chapters or volumes is compared if totals differ.
+func sameMangaProgress(progress, total, targetProgress, targetTotal int) bool {
+	if progress == targetProgress {
+		return true
+	}
+	if total == targetTotal || total == 0 || targetTotal == 0 {
+		return false
+	}
+	return total-progress == targetTotal-targetProgress
+}
+
 func (m Manga) SameTypeWithTarget(t Target) bool {
 	b, ok := t.(Manga)
 	if !ok {
@@ -123,15 +176,20 @@ func (m Manga) SameTypeWithTarget(t Target) bool {
 		return true
 	}
 
-	if m.TitleEN != "" && b.TitleEN != "" && strings.EqualFold(m.TitleEN, b.TitleEN) {
+	if m.IsAdult && !b.IsAdult {
+		DPrintf("Adult source doesn't match non-adult target: %s", b.String())
+		return false
+	}
+
+	if m.TitleEN != "" && b.TitleEN != "" && normalizeTitle(m.TitleEN) == normalizeTitle(b.TitleEN) {
 		return true
 	}
 
-	if m.TitleJP != "" && b.TitleJP != "" && strings.EqualFold(m.TitleJP, b.TitleJP) {
+	if m.TitleJP != "" && b.TitleJP != "" && normalizeRomajiTitle(m.TitleJP) == normalizeRomajiTitle(b.TitleJP) {
 		return true
 	}
 
-	if m.TitleRomaji != "" && b.TitleRomaji != "" && strings.EqualFold(m.TitleRomaji, b.TitleRomaji) {
+	if m.TitleRomaji != "" && b.TitleRomaji != "" && normalizeRomajiTitle(m.TitleRomaji) == normalizeRomajiTitle(b.TitleRomaji) {
 		return true
 	}
 
@@ -144,6 +202,129 @@ func (m Manga) SameTypeWithTarget(t Target) bool {
 	return false
 }
 
+// MatchScoreWithTarget ranks title search candidates, the higher the better.
+func (m Manga) MatchScoreWithTarget(t Target) float64 {
+	b, ok := t.(Manga)
+	if !ok {
+		return 0
+	}
+
+	score := titleSimilarity(
+		[2]string{m.TitleEN, b.TitleEN},
+		[2]string{normalizeRomajiTitle(m.TitleJP), normalizeRomajiTitle(b.TitleJP)},
+		[2]string{normalizeRomajiTitle(m.TitleRomaji), normalizeRomajiTitle(b.TitleEN)},
+	)
+	score += closeness(m.Chapters, b.Chapters)
+	score += closeness(m.Volumes, b.Volumes)
+	if sameFormat(m.Format, b.Format) {
+		score++
+	}
+	return score
+}
+
+// MergeWithTarget replaces inferred dates by the real ones from the target.
+func (m Manga) MergeWithTarget(t Target) Source {
+	b, ok := t.(Manga)
+	if !ok {
+		return m
+	}
+
+	if m.StartedAtInferred && b.StartedAt != nil {
+		m.StartedAt, m.StartedAtInferred = b.StartedAt, false
+	}
+	if m.FinishedAtInferred && b.FinishedAt != nil {
+		m.FinishedAt, m.FinishedAtInferred = b.FinishedAt, false
+	}
+
+	return m
+}
+
+// WithFieldAuthority takes the fields with MAL authority from the target.
+func (m Manga) WithFieldAuthority(t Target, c FieldAuthorityConfig) Source {
+	b, ok := t.(Manga)
+	if !ok {
+		return m
+	}
+
+	if useTarget(c.Status, 0, 0, m.UpdatedAt, b.UpdatedAt) {
+		m.Status = b.Status
+	}
+	if useTarget(c.Score, m.Score, b.Score, m.UpdatedAt, b.UpdatedAt) {
+		m.Score = b.Score
+	}
+	if useTarget(c.Progress, float64(m.Progress), float64(b.Progress), m.UpdatedAt, b.UpdatedAt) {
+		m.Progress, m.ProgressVolumes = b.Progress, b.ProgressVolumes
+	}
+	if useTarget(c.Dates, 0, 0, m.UpdatedAt, b.UpdatedAt) {
+		m.StartedAt, m.StartedAtInferred = b.StartedAt, false
+		m.FinishedAt, m.FinishedAtInferred = b.FinishedAt, false
+	}
+
+	return m
+}
+
+// WithoutProgressRegress keeps the target chapters and volumes progress when it is ahead of the source.
+// It reports whether the source progress was replaced.
+func (m Manga) WithoutProgressRegress(t Target) (Source, bool) {
+	b, ok := t.(Manga)
+	if !ok {
+		return m, false
+	}
+
+	var regressed bool
+	if b.Progress > m.Progress {
+		DPrintf("Progress: target is ahead: %d > %d", b.Progress, m.Progress)
+		m.Progress, regressed = b.Progress, true
+	}
+	if b.ProgressVolumes > m.ProgressVolumes {
+		DPrintf("ProgressVolumes: target is ahead: %d > %d", b.ProgressVolumes, m.ProgressVolumes)
+		m.ProgressVolumes, regressed = b.ProgressVolumes, true
+	}
+	return m, regressed
+}
+
+// withNotesMapping uses MAL ID from AniList notes instead of the one from AniList database.
+func (m Manga) withNotesMapping() Manga {
+	if m.NotesIDMal == 0 || m.NotesIDMal == m.IDMal {
+		return m
+	}
+	log.Printf("Using MAL id %d from notes instead of %d: %s", m.NotesIDMal, m.IDMal, m.GetTitle())
+	m.IDMal = m.NotesIDMal
+	return m
+}
+
+// withRereadingAsCompleted marks rereading manga as completed with all chapters and volumes read.
+func (m Manga) withRereadingAsCompleted() Manga {
+	if !m.Rereading {
+		return m
+	}
+	m.Status = MangaStatusCompleted
+	m.Rereading = false
+	if m.Chapters > 0 {
+		m.Progress = m.Chapters
+	}
+	if m.Volumes > 0 {
+		m.ProgressVolumes = m.Volumes
+	}
+	return m
+}
+
+// withInferredDates fills missing finish date of completed manga by the last update time
+// and missing start date of reading manga by the time it was added to the list.
+func (m Manga) withInferredDates(loc *time.Location) Manga {
+	if m.Status == MangaStatusCompleted && m.FinishedAt == nil && m.UpdatedAt != nil {
+		d := dateIn(*m.UpdatedAt, loc)
+		m.FinishedAt, m.FinishedAtInferred = &d, true
+		DPrintf("Inferred finish date for %s: %s", m.GetTitle(), d.Format(time.DateOnly))
+	}
+	if m.Status == MangaStatusReading && m.Progress > 0 && m.StartedAt == nil && m.CreatedAt != nil {
+		d := dateIn(*m.CreatedAt, loc)
+		m.StartedAt, m.StartedAtInferred = &d, true
+		DPrintf("Inferred start date for %s: %s", m.GetTitle(), d.Format(time.DateOnly))
+	}
+	return m
+}
+
 func (m Manga) GetUpdateMyAnimeListStatusOption() []mal.UpdateMyAnimeListStatusOption {
 	return nil
 }
@@ -171,42 +352,68 @@ func (m Manga) String() string {
 	sb.WriteString(fmt.Sprintf("ProgressVolumes: %d, ", m.ProgressVolumes))
 	sb.WriteString(fmt.Sprintf("Chapters: %d, ", m.Chapters))
 	sb.WriteString(fmt.Sprintf("Volumes: %d, ", m.Volumes))
+	sb.WriteString(fmt.Sprintf("Rereading: %t, ", m.Rereading))
+	sb.WriteString(fmt.Sprintf("Repeat: %d, ", m.Repeat))
 	sb.WriteString(fmt.Sprintf("StartedAt: %s, ", m.StartedAt))
 	sb.WriteString(fmt.Sprintf("FinishedAt: %s", m.FinishedAt))
 	sb.WriteString("}")
 	return sb.String()
 }
 
-func (m Manga) GetUpdateOptions() []mal.UpdateMyMangaListStatusOption {
+// syncsDates reports whether start and finish dates of the entry are synced.
+func (m Manga) syncsDates(o EntryOptions) bool {
+	return !o.DatesTerminalOnly || m.Status == MangaStatusCompleted || m.Status == MangaStatusDropped
+}
+
+func (m Manga) GetUpdateOptions(o EntryOptions) []mal.UpdateMyMangaListStatusOption {
 	st, err := m.Status.GetMalStatus()
 	if err != nil {
 		log.Printf("Error getting MAL status: %v", err)
 		return nil
 	}
 
+	if o.OnlyStatusChanges {
+		return []mal.UpdateMyMangaListStatusOption{st}
+	}
+
 	opts := []mal.UpdateMyMangaListStatusOption{
 		st,
-		mal.Score(m.Score),
 		mal.NumChaptersRead(m.Progress),
 		mal.NumVolumesRead(m.ProgressVolumes),
+		mal.IsRereading(m.Rereading),
+		mal.NumTimesReread(m.Repeat),
+	}
+
+	if o.syncsScore(m.Score) {
+		opts = append(opts, mal.Score(m.Score))
+	}
+
+	if m.syncsDates(o) {
+		if m.StartedAt != nil {
+			opts = append(opts, mal.StartDate(m.StartedAt.In(o.location())))
+		} else if !o.DatesNoClear {
+			opts = append(opts, mal.StartDate(time.Time{}))
+		}
+
+		if m.Status == MangaStatusCompleted && m.FinishedAt != nil {
+			opts = append(opts, mal.FinishDate(m.FinishedAt.In(o.location())))
+		} else if !o.DatesNoClear {
+			opts = append(opts, mal.FinishDate(time.Time{}))
+		}
 	}
 
-	if m.StartedAt != nil {
-		opts = append(opts, mal.StartDate(*m.StartedAt))
-	} else {
-		opts = append(opts, mal.StartDate(time.Time{}))
+	if m.Priority != nil {
+		opts = append(opts, mal.Priority(*m.Priority))
 	}
 
-	if m.Status == MangaStatusCompleted && m.FinishedAt != nil {
-		opts = append(opts, mal.FinishDate(*m.FinishedAt))
-	} else {
-		opts = append(opts, mal.FinishDate(time.Time{}))
+	if m.RereadValue != nil {
+		opts = append(opts, mal.RereadValue(*m.RereadValue))
 	}
 
 	return opts
 }
 
-func newMangaFromMediaListEntry(mediaList verniy.MediaList) (Manga, error) {
+func newMangaFromMediaListEntry(mediaList verniy.MediaList, loc *time.Location) (Manga, error) {
 	if mediaList.Media == nil {
 		return Manga{}, errors.New("media is nil")
 	}
@@ -264,8 +471,18 @@ func newMangaFromMediaListEntry(mediaList verniy.MediaList) (Manga, error) {
 		volumes = *mediaList.Media.Volumes
 	}
 
-	startedAt := convertFuzzyDateToTimeOrNow(mediaList.StartedAt)
-	finishedAt := convertFuzzyDateToTimeOrNow(mediaList.CompletedAt)
+	var format string
+	if mediaList.Media.Format != nil {
+		format = string(*mediaList.Media.Format)
+	}
+
+	var repeat int
+	if mediaList.Repeat != nil {
+		repeat = *mediaList.Repeat
+	}
+
+	startedAt := convertFuzzyDateToTimeOrNow(mediaList.StartedAt, loc)
+	finishedAt := convertFuzzyDateToTimeOrNow(mediaList.CompletedAt, loc)
 
 	return Manga{
 		IDAnilist:       mediaList.Media.ID,
@@ -281,16 +498,32 @@ func newMangaFromMediaListEntry(mediaList verniy.MediaList) (Manga, error) {
 		Volumes:         volumes,
 		StartedAt:       startedAt,
 		FinishedAt:      finishedAt,
+		Format:          format,
+		Genres:          mediaList.Media.Genres,
+		IsAdult:         mediaList.Media.IsAdult != nil && *mediaList.Media.IsAdult,
+		Rereading:       *mediaList.Status == verniy.MediaListStatusRepeating,
+		Repeat:          repeat,
+		UpdatedAt:       convertUnixToTime(mediaList.UpdatedAt),
+		CreatedAt:       convertUnixToTime(mediaList.CreatedAt),
+
+		HiddenFromStatusLists: mediaList.HiddenFromStatusLists != nil && *mediaList.HiddenFromStatusLists,
+		AnilistStatus:         *mediaList.Status,
+		NotesIDMal:            malIDFromNotes(mediaList.Notes),
 	}, nil
 }
 
-func newMangaFromMalManga(manga mal.Manga) (Manga, error) {
+func newMangaFromMalManga(manga mal.Manga, loc *time.Location) (Manga, error) {
 	if manga.ID == 0 {
 		return Manga{}, errors.New("ID is nil")
 	}
 
-	startedAt := parseDateOrNow(manga.MyListStatus.StartDate)
-	finishedAt := parseDateOrNow(manga.MyListStatus.FinishDate)
+	startedAt := parseDateOrNow(manga.MyListStatus.StartDate, loc)
+	finishedAt := parseDateOrNow(manga.MyListStatus.FinishDate, loc)
+
+	var updatedAt *time.Time
+	if !manga.MyListStatus.UpdatedAt.IsZero() {
+		updatedAt = &manga.MyListStatus.UpdatedAt
+	}
 
 	titleEN := manga.Title
 	if manga.AlternativeTitles.En != "" {
@@ -302,6 +535,11 @@ func newMangaFromMalManga(manga mal.Manga) (Manga, error) {
 		titleJP = manga.AlternativeTitles.Ja
 	}
 
+	var priority, rereadValue *int
+	if manga.MyListStatus.Status != "" {
+		priority, rereadValue = &manga.MyListStatus.Priority, &manga.MyListStatus.RereadValue
+	}
+
 	return Manga{
 		IDAnilist:       -1,
 		IDMal:           manga.ID,
@@ -316,6 +554,16 @@ func newMangaFromMalManga(manga mal.Manga) (Manga, error) {
 		Volumes:         manga.NumVolumes,
 		StartedAt:       startedAt,
 		FinishedAt:      finishedAt,
+		Format:          manga.MediaType,
+		Rereading:       manga.MyListStatus.IsRereading,
+		Repeat:          manga.MyListStatus.NumTimesReread,
+		IsAdult:         manga.Nsfw == malNSFWBlack,
+		UpdatedAt:       updatedAt,
+
+		CommentsIDAnilist: anilistIDFromComments(manga.MyListStatus.Comments),
+
+		Priority:    priority,
+		RereadValue: rereadValue,
 	}, nil
 }
 
@@ -349,17 +597,17 @@ func mapAnilistMangaStatustToStatus(s verniy.MediaListStatus) MangaStatus {
 	case verniy.MediaListStatusPlanning:
 		return MangaStatusPlanToRead
 	case verniy.MediaListStatusRepeating:
-		return MangaStatusReading // TODO: handle repeating correctly
+		return MangaStatusReading // rereading flag is set separately
 	default:
 		return MangaStatusUnknown
 	}
 }
 
-func newMangasFromMediaListGroups(groups []verniy.MediaListGroup) []Manga {
+func newMangasFromMediaListGroups(groups []verniy.MediaListGroup, loc *time.Location) []Manga {
 	res := make([]Manga, 0, len(groups))
 	for _, group := range groups {
 		for _, mediaList := range group.Entries {
-			r, err := newMangaFromMediaListEntry(mediaList)
+			r, err := newMangaFromMediaListEntry(mediaList, loc)
 			if err != nil {
 				log.Printf("Error creating manga from media list entry: %v", err)
 				continue
@@ -371,10 +619,11 @@ func newMangasFromMediaListGroups(groups []verniy.MediaListGroup) []Manga {
 	return res
 }
 
-func newMangasFromMalUserMangas(mangas []mal.UserManga) []Manga {
+func newMangasFromMalUserMangas(mangas []mal.UserManga, loc *time.Location) []Manga {
 	res := make([]Manga, 0, len(mangas))
 	for _, manga := range mangas {
-		r, err := newMangaFromMalManga(manga.Manga)
+		manga.Manga.MyListStatus = manga.Status
+		r, err := newMangaFromMalManga(manga.Manga, loc)
 		if err != nil {
 			log.Printf("Error creating manga from mal user manga: %v", err)
 			continue
@@ -385,10 +634,10 @@ func newMangasFromMalUserMangas(mangas []mal.UserManga) []Manga {
 	return res
 }
 
-func newMangasFromMalMangas(mangas []mal.Manga) []Manga {
+func newMangasFromMalMangas(mangas []mal.Manga, loc *time.Location) []Manga {
 	res := make([]Manga, 0, len(mangas))
 	for _, manga := range mangas {
-		r, err := newMangaFromMalManga(manga)
+		r, err := newMangaFromMalManga(manga, loc)
 		if err != nil {
 			log.Printf("Error creating manga from mal manga: %v", err)
 			continue
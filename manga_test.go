@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rl404/verniy"
+)
+
+func TestMangaSameProgressWithTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		src  Manga
+		tgt  Manga
+		want bool
+	}{
+		{
+			"unknown total on both sides",
+			Manga{Status: MangaStatusReading, Progress: 50},
+			Manga{Status: MangaStatusReading, Progress: 50},
+			true,
+		},
+		{
+			"unknown total on both sides, progress differs",
+			Manga{Status: MangaStatusReading, Progress: 50},
+			Manga{Status: MangaStatusReading, Progress: 49},
+			false,
+		},
+		{
+			"unknown source total",
+			Manga{Status: MangaStatusReading, Progress: 50},
+			Manga{Status: MangaStatusReading, Progress: 50, Chapters: 120},
+			true,
+		},
+		{
+			"unknown target total, progress differs",
+			Manga{Status: MangaStatusReading, Progress: 50, Chapters: 120},
+			Manga{Status: MangaStatusReading, Progress: 40},
+			false,
+		},
+		{
+			"different totals, same remaining chapters",
+			Manga{Status: MangaStatusReading, Progress: 52, Chapters: 62},
+			Manga{Status: MangaStatusReading, Progress: 50, Chapters: 60},
+			true,
+		},
+		{
+			"same totals, progress differs",
+			Manga{Status: MangaStatusReading, Progress: 52, Chapters: 60},
+			Manga{Status: MangaStatusReading, Progress: 50, Chapters: 60},
+			false,
+		},
+		{
+			"unknown volumes total",
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 5},
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 5, Volumes: 12},
+			true,
+		},
+		{
+			"different volume totals, same remaining volumes",
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 6, Volumes: 8},
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 5, Volumes: 7},
+			true,
+		},
+		{
+			"unknown volume total, volumes differ",
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 6},
+			Manga{Status: MangaStatusReading, Progress: 50, ProgressVolumes: 5, Volumes: 7},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.src.SameProgressWithTarget(tt.tgt, EntryOptions{}); got != tt.want {
+				t.Errorf("SameProgressWithTarget() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMangaCompletedThenRereading(t *testing.T) {
+	src := mangaFromAnilist(t, `{"status": "REPEATING", "progress": 10, "repeat": 1,
+		"media": {"id": 30002, "idMal": 2, "title": {"romaji": "Berserk"}, "chapters": 380}}`)
+	if src.Status != MangaStatusReading || !src.Rereading {
+		t.Fatalf("got status %s, rereading %t, want reading and rereading", src.Status, src.Rereading)
+	}
+
+	completed := mangaFromMAL(t, `{"id": 2, "title": "Berserk", "num_chapters": 380,
+		"my_list_status": {"status": "completed", "num_chapters_read": 380, "num_times_reread": 1}}`)
+	if src.SameProgressWithTarget(completed, EntryOptions{}) {
+		t.Error("rereading manga is the same as completed one")
+	}
+	var rereading bool
+	for _, opt := range src.GetUpdateOptions(EntryOptions{}) {
+		if v, ok := opt.(mal.IsRereading); ok {
+			rereading = bool(v)
+		}
+	}
+	if !rereading {
+		t.Error("is_rereading isn't sent")
+	}
+
+	// MAL has it as sent, so the next run doesn't update it again.
+	updated := mangaFromMAL(t, `{"id": 2, "title": "Berserk", "num_chapters": 380,
+		"my_list_status": {"status": "reading", "is_rereading": true, "num_chapters_read": 10, "num_times_reread": 1}}`)
+	if !src.SameProgressWithTarget(updated, EntryOptions{}) {
+		t.Errorf("%s isn't the same as %s", src, updated)
+	}
+}
+
+func mangaFromMAL(t *testing.T, data string) Manga {
+	t.Helper()
+	var m mal.Manga
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		t.Fatal(err)
+	}
+	res, err := newMangaFromMalManga(m, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestMangaRereadCount(t *testing.T) {
+	src := mangaFromAnilist(t, `{"status": "COMPLETED", "progress": 380, "repeat": 2,
+		"media": {"id": 30002, "idMal": 2, "title": {"romaji": "Berserk"}, "chapters": 380}}`)
+
+	tests := []struct {
+		name        string
+		tgt         string
+		wantUpdates int
+	}{
+		{"same count", `{"id": 2, "title": "Berserk", "num_chapters": 380,
+			"my_list_status": {"status": "completed", "num_chapters_read": 380, "num_times_reread": 2}}`, 0},
+		{"MAL count behind", `{"id": 2, "title": "Berserk", "num_chapters": 380,
+			"my_list_status": {"status": "completed", "num_chapters_read": 380, "num_times_reread": 1}}`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rereads []int
+			u := &Updater{
+				Prefix:     "Manga",
+				Statistics: new(Statistics),
+				UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, o EntryOptions) error {
+					for _, opt := range src.(Manga).GetUpdateOptions(o) {
+						if v, ok := opt.(mal.NumTimesReread); ok {
+							rereads = append(rereads, int(v))
+						}
+					}
+					return nil
+				},
+			}
+			u.Update(context.Background(), []Source{src}, []Target{mangaFromMAL(t, tt.tgt)})
+
+			if len(rereads) != tt.wantUpdates {
+				t.Fatalf("got %d updates, want %d", len(rereads), tt.wantUpdates)
+			}
+			if len(rereads) > 0 && rereads[0] != 2 {
+				t.Errorf("sent reread count %d, want 2", rereads[0])
+			}
+		})
+	}
+}
+
+func mangaFromAnilist(t *testing.T, data string) Manga {
+	t.Helper()
+	var entry verniy.MediaList
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		t.Fatal(err)
+	}
+	res, err := newMangaFromMediaListEntry(entry, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/rl404/verniy"
+)
+
+// Problems of "mal:<id>" notes mappings found by CheckMappings.
+const (
+	mappingRedundant = "redundant" // AniList has the same MAL ID
+	mappingDangling  = "dangling"  // MAL entry doesn't exist
+)
+
+// mappingRow is a stale "mal:<id>" mapping in AniList entry notes.
+type mappingRow struct {
+	Type         string `json:"type" yaml:"type"`
+	IDAnilist    int    `json:"id_anilist" yaml:"id_anilist"`
+	Title        string `json:"title" yaml:"title"`
+	NotesIDMal   int    `json:"notes_id_mal" yaml:"notes_id_mal"`
+	AnilistIDMal int    `json:"anilist_id_mal" yaml:"anilist_id_mal"` // 0 if AniList has none
+	Problem      string `json:"problem" yaml:"problem"`
+}
+
+type mappingRows []mappingRow
+
+func (mappingRows) Header() []string {
+	return []string{"type", "anilist id", "title", "notes mal id", "anilist mal id", "problem"}
+}
+
+func (rows mappingRows) Rows() [][]string {
+	res := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, []string{
+			r.Type,
+			strconv.Itoa(r.IDAnilist),
+			r.Title,
+			strconv.Itoa(r.NotesIDMal),
+			strconv.Itoa(r.AnilistIDMal),
+			r.Problem,
+		})
+	}
+	return res
+}
+
+// CheckMappings writes "mal:<id>" notes mappings which are redundant, because AniList
+// has the same MAL ID, or dangling, because there is no such MAL entry.
+// With -prune-mappings they are removed from AniList notes too, unless it is a dry run.
+func (a *App) CheckMappings(ctx context.Context, f Formatter, w io.Writer) error {
+	if *pruneNotesMapping && !(*dryRun) && a.anilist == nil {
+		return errors.New("-prune-mappings can't update AniList list read from -source-file")
+	}
+
+	var rows mappingRows
+	notes := make(map[int]string) // by AniList ID
+
+	if *mangaSync || *allSync {
+		groups, err := a.anilistMangaList(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting user manga list from anilist: %w", err)
+		}
+		addMediaListNotes(notes, groups)
+		var srcs []Source
+		for _, m := range newMangasFromMediaListGroups(groups, a.datesLocation) {
+			if m.NotesIDMal != 0 {
+				srcs = append(srcs, m)
+			}
+		}
+		mangaRows, err := checkMappings(ctx, a.mangaUpdater, srcs)
+		if err != nil {
+			return fmt.Errorf("error checking manga mappings: %w", err)
+		}
+		rows = append(rows, mangaRows...)
+	}
+
+	if !(*mangaSync) || *allSync {
+		groups, err := a.anilistAnimeList(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting user anime list from anilist: %w", err)
+		}
+		addMediaListNotes(notes, groups)
+		var srcs []Source
+		for _, ani := range newAnimesFromMediaListGroups(groups, a.datesLocation) {
+			if ani.NotesIDMal != 0 {
+				srcs = append(srcs, ani)
+			}
+		}
+		animeRows, err := checkMappings(ctx, a.animeUpdater, srcs)
+		if err != nil {
+			return fmt.Errorf("error checking anime mappings: %w", err)
+		}
+		rows = append(rows, animeRows...)
+	}
+
+	if err := f.Format(w, rows); err != nil {
+		return err
+	}
+
+	if *pruneNotesMapping {
+		var save func(context.Context, int, string) error
+		if a.anilist != nil {
+			save = a.anilist.SaveNotes
+		}
+		if err := pruneMappings(ctx, rows, notes, save); err != nil {
+			return fmt.Errorf("error pruning mappings: %w", err)
+		}
+	}
+	return nil
+}
+
+// addMediaListNotes adds notes of the list entries by AniList ID.
+func addMediaListNotes(notes map[int]string, groups []verniy.MediaListGroup) {
+	for _, g := range groups {
+		for _, e := range g.Entries {
+			if e.Media != nil && e.Notes != nil {
+				notes[e.Media.ID] = *e.Notes
+			}
+		}
+	}
+}
+
+// pruneMappings removes the stale mappings from notes of their AniList entries with save.
+// Nothing is saved on dry run.
+func pruneMappings(ctx context.Context, rows mappingRows, notes map[int]string, save func(context.Context, int, string) error) error {
+	for _, r := range rows {
+		pruned := withoutMalIDToken(notes[r.IDAnilist], r.NotesIDMal)
+		if *dryRun {
+			log.Printf("Dry run: Skipping removal of %s mal:%d from notes: %s", r.Problem, r.NotesIDMal, r.Title)
+			continue
+		}
+		if err := save(ctx, r.IDAnilist, pruned); err != nil {
+			return fmt.Errorf("%s: %w", r.Title, err)
+		}
+		log.Printf("Removed %s mal:%d from notes: %s", r.Problem, r.NotesIDMal, r.Title)
+	}
+	return nil
+}
+
+// withoutMalIDToken removes "mal:<id>" tokens from notes, lines without them are kept as they are
+// and lines with only them are removed.
+func withoutMalIDToken(notes string, id int) string {
+	token := "mal:" + strconv.Itoa(id)
+
+	lines := strings.Split(notes, "\n")
+	res := lines[:0]
+	for _, line := range lines {
+		words := strings.Fields(line)
+		kept := make([]string, 0, len(words))
+		for _, w := range words {
+			if w != token {
+				kept = append(kept, w)
+			}
+		}
+		switch {
+		case len(kept) == len(words):
+			res = append(res, line)
+		case len(kept) > 0:
+			res = append(res, strings.Join(kept, " "))
+		}
+	}
+	return strings.Join(res, "\n")
+}
+
+// checkMappings checks mappings of the sources, each MAL ID is looked up once.
+func checkMappings(ctx context.Context, u *Updater, srcs []Source) (mappingRows, error) {
+	exists := make(map[int]bool)
+
+	var rows mappingRows
+	for _, src := range srcs {
+		var notesID, anilistID int
+		switch v := src.(type) {
+		case Anime:
+			notesID, anilistID = v.NotesIDMal, v.IDMal
+		case Manga:
+			notesID, anilistID = v.NotesIDMal, v.IDMal
+		}
+
+		row := mappingRow{
+			Type:         strings.ToLower(u.Prefix),
+			IDAnilist:    sourceAnilistID(src),
+			Title:        u.title(src),
+			NotesIDMal:   notesID,
+			AnilistIDMal: anilistID,
+		}
+
+		if notesID == anilistID {
+			row.Problem = mappingRedundant
+			rows = append(rows, row)
+			continue
+		}
+
+		ok, checked := exists[notesID]
+		if !checked {
+			_, err := u.GetTargetByIDFunc(ctx, TargetID(notesID))
+			if err != nil && !errors.Is(err, errMalNotAccessible) {
+				return nil, err
+			}
+			ok = err == nil
+			exists[notesID] = ok
+		}
+		if !ok {
+			row.Problem = mappingDangling
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckMappings(t *testing.T) {
+	lookups := make(map[TargetID]int)
+	u := &Updater{
+		Prefix: "Anime",
+		GetTargetByIDFunc: func(_ context.Context, id TargetID) (Target, error) {
+			lookups[id]++
+			if id == 999 {
+				return nil, errMalNotAccessible
+			}
+			return Anime{IDMal: int(id)}, nil
+		},
+	}
+	srcs := []Source{
+		Anime{IDAnilist: 1, IDMal: 100, NotesIDMal: 100, TitleEN: "Redundant"},
+		Anime{IDAnilist: 2, IDMal: 200, NotesIDMal: 999, TitleEN: "Dangling"},
+		Anime{IDAnilist: 3, IDMal: 300, NotesIDMal: 301, TitleEN: "Valid"},
+		Anime{IDAnilist: 4, IDMal: 0, NotesIDMal: 999, TitleEN: "Dangling too"},
+	}
+
+	rows, err := checkMappings(context.Background(), u, srcs)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	want := []struct {
+		id      int
+		problem string
+	}{{1, mappingRedundant}, {2, mappingDangling}, {4, mappingDangling}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		if rows[i].IDAnilist != w.id || rows[i].Problem != w.problem {
+			t.Errorf("row %d: got %d %s, want %d %s", i, rows[i].IDAnilist, rows[i].Problem, w.id, w.problem)
+		}
+	}
+	if lookups[999] != 1 {
+		t.Errorf("got %d lookups of the dangling MAL ID, want 1", lookups[999])
+	}
+	if _, ok := lookups[100]; ok {
+		t.Error("redundant mapping was looked up")
+	}
+}
+
+func TestPruneMappings(t *testing.T) {
+	rows := mappingRows{
+		{IDAnilist: 1, NotesIDMal: 100, Problem: mappingRedundant, Title: "Redundant"},
+		{IDAnilist: 2, NotesIDMal: 999, Problem: mappingDangling, Title: "Dangling"},
+	}
+	notes := map[int]string{
+		1: "mal:100",
+		2: "great ending mal:999\nrewatch",
+	}
+
+	saved := make(map[int]string)
+	save := func(_ context.Context, id int, notes string) error {
+		saved[id] = notes
+		return nil
+	}
+
+	*dryRun = true
+	t.Cleanup(func() { *dryRun = false })
+	if err := pruneMappings(context.Background(), rows, notes, save); err != nil {
+		t.Fatalf("dry run: got error %v", err)
+	}
+	if len(saved) != 0 {
+		t.Fatalf("dry run saved notes: %v", saved)
+	}
+
+	*dryRun = false
+	if err := pruneMappings(context.Background(), rows, notes, save); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	want := map[int]string{1: "", 2: "great ending\nrewatch"}
+	for id, w := range want {
+		if got, ok := saved[id]; !ok || got != w {
+			t.Errorf("notes of %d: got %q, want %q", id, got, w)
+		}
+	}
+}
+
+func TestWithoutMalIDToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes string
+		want  string
+	}{
+		{"only token", "mal:1", ""},
+		{"token among words", "see mal:1 later", "see later"},
+		{"token line", "first\nmal:1\nlast", "first\nlast"},
+		{"other lines untouched", "  indented\nmal:1 x", "  indented\nx"},
+		{"other id", "mal:12", "mal:12"},
+		{"not a word", "animal:1", "animal:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withoutMalIDToken(tt.notes, 1); got != tt.want {
+				t.Errorf("withoutMalIDToken(%q) = %q, want %q", tt.notes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import "strings"
+
+// defaultAmbiguityMargin is the min lead of the best title search candidate
+// over the second one, closer candidates are ambiguous and none is picked.
+const defaultAmbiguityMargin = 0.1
+
+// titleSimilarity returns 1 if any pair of titles is equal after normalization,
+// 0.5 if one title of a pair contains another and 0 otherwise.
+// Pairs with romaji or native titles are passed normalized with normalizeRomajiTitle.
+func titleSimilarity(pairs ...[2]string) float64 {
+	var res float64
+	for _, p := range pairs {
+		s1, s2 := normalizeTitle(p[0]), normalizeTitle(p[1])
+		if s1 == "" || s2 == "" {
+			continue
+		}
+		if s1 == s2 {
+			return 1
+		}
+		if strings.Contains(s1, s2) || strings.Contains(s2, s1) {
+			res = 0.5
+		}
+	}
+	return res
+}
+
+// sameTitle reports whether any title of the source equals a title of the target after normalization.
+func sameTitle(src Source, tgt Target) bool {
+	switch a := src.(type) {
+	case Anime:
+		b, ok := tgt.(Anime)
+		return ok && titleSimilarity(
+			[2]string{a.TitleEN, b.TitleEN},
+			[2]string{normalizeRomajiTitle(a.TitleJP), normalizeRomajiTitle(b.TitleJP)},
+			[2]string{normalizeRomajiTitle(a.TitleRomaji), normalizeRomajiTitle(b.TitleEN)},
+		) == 1
+	case Manga:
+		b, ok := tgt.(Manga)
+		return ok && titleSimilarity(
+			[2]string{a.TitleEN, b.TitleEN},
+			[2]string{normalizeRomajiTitle(a.TitleJP), normalizeRomajiTitle(b.TitleJP)},
+			[2]string{normalizeRomajiTitle(a.TitleRomaji), normalizeRomajiTitle(b.TitleEN)},
+		) == 1
+	default:
+		return false
+	}
+}
+
+// seasonYearsClose reports whether season years of anime matched by title differ
+// by at most tolerance. Unknown years, the same MAL ID and negative tolerance pass.
+func seasonYearsClose(src Source, tgt Target, tolerance int) bool {
+	a, ok := src.(Anime)
+	if !ok {
+		return true
+	}
+	b, ok := tgt.(Anime)
+	if !ok || tolerance < 0 || a.IDMal == b.IDMal || a.SeasonYear <= 0 || b.SeasonYear <= 0 {
+		return true
+	}
+
+	d := a.SeasonYear - b.SeasonYear
+	if d > tolerance || -d > tolerance {
+		DPrintf("Season years are too far: %d, %d", a.SeasonYear, b.SeasonYear)
+		return false
+	}
+	return true
+}
+
+// closeness returns 1 for equal numbers and less the more they differ,
+// unknown (zero) numbers give 0.
+func closeness(a, b int) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	return 1 / float64(1+d)
+}
+
+// sameFormat compares AniList format with MAL media type.
+func sameFormat(anilist, mal string) bool {
+	if anilist == "" || mal == "" {
+		return false
+	}
+
+	a := strings.ToLower(anilist)
+	if a == "tv_short" {
+		a = "tv"
+	}
+
+	m := strings.ToLower(mal)
+	switch m {
+	case "tv_special":
+		m = "special"
+	case "light_novel":
+		m = "novel"
+	case "manhwa", "manhua", "oel", "doujinshi":
+		m = "manga"
+	}
+
+	return a == m
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSeasonYearsClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		src, tgt  Anime
+		tolerance int
+		want      bool
+	}{
+		{"same year", Anime{IDMal: 1, SeasonYear: 2015}, Anime{IDMal: 2, SeasonYear: 2015}, 1, true},
+		{"within tolerance", Anime{IDMal: 1, SeasonYear: 2015}, Anime{IDMal: 2, SeasonYear: 2016}, 1, true},
+		{"too far", Anime{IDMal: 1, SeasonYear: 2024}, Anime{IDMal: 2, SeasonYear: 2015}, 1, false},
+		{"too far, disabled", Anime{IDMal: 1, SeasonYear: 2024}, Anime{IDMal: 2, SeasonYear: 2015}, -1, true},
+		{"too far, same id", Anime{IDMal: 2, SeasonYear: 2024}, Anime{IDMal: 2, SeasonYear: 2015}, 1, true},
+		{"unknown year", Anime{IDMal: 1}, Anime{IDMal: 2, SeasonYear: 2015}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seasonYearsClose(tt.src, tt.tgt, tt.tolerance); got != tt.want {
+				t.Errorf("seasonYearsClose() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTargetByNameYearTolerance(t *testing.T) {
+	src := Anime{IDAnilist: 10, TitleEN: "Fate/stay night", TitleJP: "フェイト", SeasonYear: 2024}
+	tv := Anime{IDMal: 1, TitleEN: "Fate/stay night", TitleJP: "フェイト", SeasonYear: 2015}
+	movie := Anime{IDMal: 2, TitleEN: "Fate/stay night", TitleJP: "フェイト", SeasonYear: 2024}
+
+	u := &Updater{
+		Statistics:      new(Statistics),
+		AmbiguityMargin: defaultAmbiguityMargin,
+		YearTolerance:   1,
+		GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+			return []Target{tv, movie}, nil
+		},
+	}
+
+	tgt, err := u.findTargetByName(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tgt == nil || tgt.GetTargetID() != movie.GetTargetID() {
+		t.Errorf("got %v, want %v", tgt, movie)
+	}
+
+	u.GetTargetsByNameFunc = func(context.Context, string) ([]Target, error) {
+		return []Target{tv}, nil
+	}
+	tgt, err = u.findTargetByName(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tgt != nil {
+		t.Errorf("got %v, want no match", tgt)
+	}
+
+	u.YearTolerance = -1
+	tgt, err = u.findTargetByName(context.Background(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tgt == nil || tgt.GetTargetID() != tv.GetTargetID() {
+		t.Errorf("got %v, want %v", tgt, tv)
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	src := Anime{IDAnilist: 10, TitleEN: "Frieren: Beyond Journey's End", TitleRomaji: "Sousou no Frieren", SeasonYear: 2023, NumEpisodes: 28, Format: "TV"}
+	tv := Anime{IDMal: 52991, TitleEN: "Frieren: Beyond Journey's End", TitleJP: "葬送のフリーレン", SeasonYear: 2023, NumEpisodes: 28, Format: "tv"}
+	sequel := Anime{IDMal: 59978, TitleEN: "Frieren: Beyond Journey's End Season 2", SeasonYear: 2026, NumEpisodes: 10, Format: "tv"}
+	special := Anime{IDMal: 56885, TitleEN: "Frieren: Beyond Journey's End Mini Anime", SeasonYear: 2023, NumEpisodes: 28, Format: "ona"}
+	// The same entry listed twice under different IDs scores the same.
+	duplicate := tv
+	duplicate.IDMal = 99999
+
+	tests := []struct {
+		name          string
+		candidates    []Target
+		margin        float64
+		want          string
+		wantAmbiguous bool
+	}{
+		{"best first", []Target{sequel, special, tv}, defaultAmbiguityMargin, "[52991 56885 59978]", false},
+		{"single", []Target{sequel}, defaultAmbiguityMargin, "[59978]", false},
+		{"tie within margin", []Target{duplicate, tv, sequel}, defaultAmbiguityMargin, "[99999 52991 59978]", true},
+		{"tie without margin", []Target{duplicate, tv, sequel}, 0, "[99999 52991 59978]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Updater{AmbiguityMargin: tt.margin}
+			ranked, ambiguous := u.rankCandidates(src, tt.candidates)
+
+			var ids []TargetID
+			for _, tgt := range ranked {
+				ids = append(ids, tgt.GetTargetID())
+			}
+			if got := fmt.Sprint(ids); got != tt.want {
+				t.Errorf("got ranking %s, want %s", got, tt.want)
+			}
+			if ambiguous != tt.wantAmbiguous {
+				t.Errorf("got ambiguous %t, want %t", ambiguous, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+func TestFindTargetByNameAskOnAmbiguous(t *testing.T) {
+	// Without terminal uncertain matches are recorded for manual review.
+	stdin := os.Stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+		w.Close()
+	})
+
+	src := Anime{IDAnilist: 10, TitleEN: "Kaguya-sama: Love is War OVA", SeasonYear: 2019}
+	exact := Anime{IDMal: 1, TitleEN: "Kaguya-sama: Love is War OVA", SeasonYear: 2019}
+	partial := Anime{IDMal: 2, TitleEN: "Kaguya-sama: Love is War", SeasonYear: 2019}
+	duplicate := Anime{IDMal: 3, TitleEN: "Kaguya-sama: Love is War OVA", SeasonYear: 2019}
+
+	tests := []struct {
+		name       string
+		ask        bool
+		candidates []Target
+		want       TargetID // 0 if nothing is matched
+		wantWarn   string
+	}{
+		{"exact", true, []Target{exact}, 1, ""},
+		{"close", true, []Target{partial}, 0, "uncertain title match"},
+		{"ambiguous", true, []Target{exact, duplicate}, 0, "uncertain title match"},
+		{"close, not asked", false, []Target{partial}, 2, ""},
+		{"ambiguous, not asked", false, []Target{exact, duplicate}, 0, "ambiguous title match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Updater{
+				Statistics:      new(Statistics),
+				AmbiguityMargin: defaultAmbiguityMargin,
+				AskOnAmbiguous:  tt.ask,
+				GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+					return tt.candidates, nil
+				},
+			}
+
+			tgt, err := u.findTargetByName(context.Background(), src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got TargetID
+			if tgt != nil {
+				got = tgt.GetTargetID()
+			}
+			if got != tt.want {
+				t.Errorf("got match %d, want %d", got, tt.want)
+			}
+
+			warns := u.Statistics.Warnings
+			if tt.wantWarn == "" && len(warns) != 0 || tt.wantWarn != "" && (len(warns) != 1 || !strings.Contains(warns[0], tt.wantWarn)) {
+				t.Errorf("got warnings %q, want %q", warns, tt.wantWarn)
+			}
+		})
+	}
+}
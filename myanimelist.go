@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -11,23 +13,62 @@ import (
 	"golang.org/x/oauth2"
 )
 
-var errEmptyMalID = errors.New("mal id is empty")
+var (
+	errEmptyMalID       = errors.New("mal id is empty")
+	errMalNotAccessible = errors.New("not accessible via MAL API")
+)
 
+// animeFields are the fields read by newAnimeFromMalAnime besides id and title, keep them in sync.
 var animeFields = mal.Fields{
 	"alternative_titles",
 	"num_episodes",
-	"my_list_status",
+	"my_list_status{comments,priority,rewatch_value}",
 	"start_season",
+	"media_type",
+	"nsfw",
 }
 
+// userAnimeFields are animeFields for the user list, which returns list status
+// of every entry as list_status, so my_list_status would only duplicate it.
+var userAnimeFields = mal.Fields{
+	"alternative_titles",
+	"num_episodes",
+	"list_status{comments,priority,rewatch_value}",
+	"start_season",
+	"media_type",
+	"nsfw",
+}
+
+// mangaFields are the fields read by newMangaFromMalManga besides id and title, keep them in sync.
 var mangaFields = mal.Fields{
 	"alternative_titles",
 	"num_volumes",
 	"num_chapters",
-	"my_list_status",
-	"start_date",
+	"my_list_status{comments,num_times_reread,priority,reread_value}",
+	"media_type",
+	"nsfw",
+}
+
+// userMangaFields are mangaFields for the user list, see userAnimeFields.
+var userMangaFields = mal.Fields{
+	"alternative_titles",
+	"num_volumes",
+	"num_chapters",
+	"list_status{comments,num_times_reread,priority,reread_value}",
+	"media_type",
+	"nsfw",
 }
 
+// malNSFWBlack is the MAL nsfw classification of adult entries,
+// it corresponds to AniList isAdult.
+const malNSFWBlack = "black"
+
+const (
+	malListPageSize = 100
+	// maxMalListPages guards against paging that never ends, it allows lists far bigger than real ones.
+	maxMalListPages = 1000
+)
+
 type MyAnimeListClient struct {
 	c *mal.Client
 
@@ -43,16 +84,37 @@ func NewMyAnimeListClient(ctx context.Context, oauth *OAuth, username string) (*
 	return &MyAnimeListClient{c: client, username: username}, nil
 }
 
+// GetMyName returns the name of the authenticated user.
+func (c *MyAnimeListClient) GetMyName(ctx context.Context) (string, error) {
+	u, _, err := c.c.User.MyInfo(ctx)
+	if err != nil {
+		return "", newSyncError(err)
+	}
+	return u.Name, nil
+}
+
 func (c *MyAnimeListClient) GetUserAnimeList(ctx context.Context) ([]mal.UserAnime, error) {
 	var userAnimeList []mal.UserAnime
-	var offset int
+	var offset, pages int
+	seen := make(map[int]struct{})
 	for {
-		list, resp, err := c.c.User.AnimeList(ctx, c.username, animeFields, mal.Offset(offset), mal.Limit(100))
-		if err != nil {
-			return nil, err
+		if pages == maxMalListPages {
+			return nil, fmt.Errorf("mal anime list has more than %d pages, stopped fetching", maxMalListPages)
 		}
 
-		userAnimeList = append(userAnimeList, list...)
+		list, resp, err := c.c.User.AnimeList(ctx, c.username, userAnimeFields, mal.Offset(offset), mal.Limit(malListPageSize))
+		if err != nil {
+			return nil, newSyncError(err)
+		}
+		pages++
+
+		for _, e := range list {
+			if _, ok := seen[e.Anime.ID]; ok {
+				continue
+			}
+			seen[e.Anime.ID] = struct{}{}
+			userAnimeList = append(userAnimeList, e)
+		}
 
 		if resp.NextOffset == 0 {
 			break
@@ -60,13 +122,14 @@ func (c *MyAnimeListClient) GetUserAnimeList(ctx context.Context) ([]mal.UserAni
 
 		offset = resp.NextOffset
 	}
+	DPrintf("Fetched %d MAL anime list entries in %d pages", len(userAnimeList), pages)
 	return userAnimeList, nil
 }
 
 func (c *MyAnimeListClient) GetAnimesByName(ctx context.Context, name string) ([]mal.Anime, error) {
 	animeList, _, err := c.c.Anime.List(ctx, name, animeFields, mal.Limit(3))
 	if err != nil {
-		return nil, err
+		return nil, newSyncError(err)
 	}
 
 	return animeList, nil
@@ -79,7 +142,7 @@ func (c *MyAnimeListClient) GetAnimeByID(ctx context.Context, id int) (*mal.Anim
 
 	anime, _, err := c.c.Anime.Details(ctx, id, animeFields)
 	if err != nil {
-		return nil, err
+		return nil, wrapMalError(err)
 	}
 
 	return anime, nil
@@ -92,21 +155,33 @@ func (c *MyAnimeListClient) UpdateAnimeByIDAndOptions(ctx context.Context, id in
 
 	_, _, err := c.c.Anime.UpdateMyListStatus(ctx, id, opts...)
 	if err != nil {
-		return err
+		return wrapMalError(err)
 	}
 	return nil
 }
 
 func (c *MyAnimeListClient) GetUserMangaList(ctx context.Context) ([]mal.UserManga, error) {
 	var userMangaList []mal.UserManga
-	var offset int
+	var offset, pages int
+	seen := make(map[int]struct{})
 	for {
-		list, resp, err := c.c.User.MangaList(ctx, c.username, mangaFields, mal.Offset(offset), mal.Limit(100))
-		if err != nil {
-			return nil, err
+		if pages == maxMalListPages {
+			return nil, fmt.Errorf("mal manga list has more than %d pages, stopped fetching", maxMalListPages)
 		}
 
-		userMangaList = append(userMangaList, list...)
+		list, resp, err := c.c.User.MangaList(ctx, c.username, userMangaFields, mal.Offset(offset), mal.Limit(malListPageSize))
+		if err != nil {
+			return nil, newSyncError(err)
+		}
+		pages++
+
+		for _, e := range list {
+			if _, ok := seen[e.Manga.ID]; ok {
+				continue
+			}
+			seen[e.Manga.ID] = struct{}{}
+			userMangaList = append(userMangaList, e)
+		}
 
 		if resp.NextOffset == 0 {
 			break
@@ -114,13 +189,14 @@ func (c *MyAnimeListClient) GetUserMangaList(ctx context.Context) ([]mal.UserMan
 
 		offset = resp.NextOffset
 	}
+	DPrintf("Fetched %d MAL manga list entries in %d pages", len(userMangaList), pages)
 	return userMangaList, nil
 }
 
 func (c *MyAnimeListClient) GetMangasByName(ctx context.Context, name string) ([]mal.Manga, error) {
 	l, _, err := c.c.Manga.List(ctx, name, mangaFields, mal.Limit(10))
 	if err != nil {
-		return nil, err
+		return nil, newSyncError(err)
 	}
 
 	return l, nil
@@ -133,7 +209,7 @@ func (c *MyAnimeListClient) GetMangaByID(ctx context.Context, id int) (*mal.Mang
 
 	m, _, err := c.c.Manga.Details(ctx, id, mangaFields)
 	if err != nil {
-		return nil, err
+		return nil, wrapMalError(err)
 	}
 
 	return m, nil
@@ -146,11 +222,27 @@ func (c *MyAnimeListClient) UpdateMangaByIDAndOptions(ctx context.Context, id in
 
 	_, _, err := c.c.Manga.UpdateMyListStatus(ctx, id, opts...)
 	if err != nil {
-		return err
+		return wrapMalError(err)
 	}
 	return nil
 }
 
+// wrapMalError categorizes errors and marks errors of entries that MAL API refuses to return or update,
+// e.g. R18, region-locked or delisted titles.
+func wrapMalError(err error) error {
+	category := classifyError(err)
+
+	var errResp *mal.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		// 403 is a revoked or under-scoped token, it stays an auth error.
+		if errResp.Response.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %v", errMalNotAccessible, err)
+		}
+	}
+
+	return &SyncError{Category: category, Err: err}
+}
+
 func NewMyAnimeListOAuth(ctx context.Context, config Config) (*OAuth, error) {
 	code := url.QueryEscape(randHttpParamString(43))
 
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nstratos/go-myanimelist/mal"
+)
+
+// malListPage is a MAL user list page with the anime IDs, next is the offset of the next page or 0.
+func malListPage(serverURL string, next int, ids ...int) string {
+	data := make([]string, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, fmt.Sprintf(`{"node":{"id":%d,"title":"Anime %d"},"list_status":{"status":"watching"}}`, id, id))
+	}
+	paging := "{}"
+	if next != 0 {
+		paging = fmt.Sprintf(`{"next":"%s/users/someone/animelist?offset=%d"}`, serverURL, next)
+	}
+	return fmt.Sprintf(`{"data":[%s],"paging":%s}`, strings.Join(data, ","), paging)
+}
+
+func TestGetUserAnimeListPages(t *testing.T) {
+	var srv *httptest.Server
+	var requests int
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("offset") {
+		case "0", "":
+			fmt.Fprint(w, malListPage(srv.URL, 2, 1, 2))
+		case "2":
+			// The list changed during the fetch, so the page starts with the last entry of the previous one.
+			fmt.Fprint(w, malListPage(srv.URL, 4, 2, 3))
+		case "4":
+			fmt.Fprint(w, malListPage(srv.URL, 0, 4))
+		default:
+			t.Errorf("unexpected offset %s", r.URL.Query().Get("offset"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := mal.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL + "/")
+	client := &MyAnimeListClient{c: c, username: "someone"}
+
+	list, err := client.GetUserAnimeList(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3 pages", requests)
+	}
+
+	var ids []int
+	for _, e := range list {
+		ids = append(ids, e.Anime.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Errorf("got anime %v, want [1 2 3 4] without duplicates", ids)
+	}
+}
+
+func TestMalRequestFields(t *testing.T) {
+	fields := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields[r.URL.Path] = r.URL.Query().Get("fields")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "list"):
+			fmt.Fprint(w, `{"data":[],"paging":{}}`)
+		default:
+			fmt.Fprint(w, `{"id":1,"title":"Frieren"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := mal.NewClient(nil)
+	c.BaseURL, _ = url.Parse(srv.URL + "/")
+	client := &MyAnimeListClient{c: c, username: "someone"}
+
+	ctx := context.Background()
+	if _, err := client.GetUserAnimeList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAnimeByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetUserMangaList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetMangaByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/someone/animelist", "alternative_titles,num_episodes,list_status{comments,priority,rewatch_value},start_season,media_type,nsfw"},
+		{"/anime/1", "alternative_titles,num_episodes,my_list_status{comments,priority,rewatch_value},start_season,media_type,nsfw"},
+		{"/users/someone/mangalist", "alternative_titles,num_volumes,num_chapters,list_status{comments,num_times_reread,priority,reread_value},media_type,nsfw"},
+		{"/manga/1", "alternative_titles,num_volumes,num_chapters,my_list_status{comments,num_times_reread,priority,reread_value},media_type,nsfw"},
+	}
+	for _, tt := range tests {
+		if got := fields[tt.path]; got != tt.want {
+			t.Errorf("%s: got fields %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
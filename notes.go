@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// notesIDRegexp matches a whole "mal:<id>" or "anilist:<id>" word,
+// so "mal:123abc" or "animal:123" aren't taken as a mapping.
+var notesIDRegexp = regexp.MustCompile(`^(mal|anilist):([1-9][0-9]{0,8})$`)
+
+// malIDFromNotes returns MAL ID from "mal:<id>" token in AniList entry notes.
+// Notes with several different MAL IDs are ambiguous and ignored.
+func malIDFromNotes(notes *string) int {
+	if notes == nil {
+		return 0
+	}
+	return idFromNotes(*notes, "mal")
+}
+
+// anilistIDFromComments returns AniList ID from "anilist:<id>" token in MAL entry comments,
+// the same way as malIDFromNotes.
+func anilistIDFromComments(comments string) int {
+	return idFromNotes(comments, "anilist")
+}
+
+func idFromNotes(notes, service string) int {
+	var id int
+	for _, word := range strings.Fields(notes) {
+		m := notesIDRegexp.FindStringSubmatch(word)
+		if m == nil || m[1] != service {
+			continue
+		}
+		v, err := strconv.Atoi(m[2])
+		if err != nil {
+			return 0
+		}
+		if id != 0 && id != v {
+			return 0
+		}
+		id = v
+	}
+	return id
+}
+
+// withCommentsMapping uses MAL ID of the entry which claims a source with "anilist:<id>" token
+// in its comments instead of the one from AniList database. Sources with "mal:<id>" notes token
+// keep it, AniList IDs claimed by several MAL entries are ambiguous and ignored.
+func withCommentsMapping(srcs []Source, tgts []Target) []Source {
+	claims := make(map[int]int, len(tgts))
+	for _, tgt := range tgts {
+		var anilistID, malID int
+		switch v := tgt.(type) {
+		case Anime:
+			anilistID, malID = v.CommentsIDAnilist, v.IDMal
+		case Manga:
+			anilistID, malID = v.CommentsIDAnilist, v.IDMal
+		}
+		if anilistID == 0 {
+			continue
+		}
+		if id, ok := claims[anilistID]; ok && id != malID {
+			claims[anilistID] = -1
+			continue
+		}
+		claims[anilistID] = malID
+	}
+	if len(claims) == 0 {
+		return srcs
+	}
+
+	res := make([]Source, 0, len(srcs))
+	for _, src := range srcs {
+		switch v := src.(type) {
+		case Anime:
+			if id := claims[v.IDAnilist]; id > 0 && v.NotesIDMal == 0 && id != v.IDMal {
+				log.Printf("Using MAL id %d from MAL comments instead of %d: %s", id, v.IDMal, v.GetTitle())
+				v.IDMal = id
+			}
+			src = v
+		case Manga:
+			if id := claims[v.IDAnilist]; id > 0 && v.NotesIDMal == 0 && id != v.IDMal {
+				log.Printf("Using MAL id %d from MAL comments instead of %d: %s", id, v.IDMal, v.GetTitle())
+				v.IDMal = id
+			}
+			src = v
+		}
+		res = append(res, src)
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestMalIDFromNotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes string
+		want  int
+	}{
+		{"token", "mal:44983", 44983},
+		{"token among words", "rewatch later mal:44983 maybe", 44983},
+		{"same token twice", "mal:1 mal:1", 1},
+		{"different tokens", "mal:1 mal:2", 0},
+		{"no token", "great show", 0},
+		{"trailing letters", "mal:123abc", 0},
+		{"word prefix", "animal:123", 0},
+		{"leading zero", "mal:0123", 0},
+		{"zero", "mal:0", 0},
+		{"too long", "mal:1234567890", 0},
+		{"uppercase", "MAL:123", 0},
+		{"no id", "mal:", 0},
+		{"anilist token", "anilist:123", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes := tt.notes
+			if got := malIDFromNotes(&notes); got != tt.want {
+				t.Errorf("malIDFromNotes(%q) = %d, want %d", tt.notes, got, tt.want)
+			}
+		})
+	}
+
+	if got := malIDFromNotes(nil); got != 0 {
+		t.Errorf("malIDFromNotes(nil) = %d, want 0", got)
+	}
+}
+
+func TestAnilistIDFromComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments string
+		want     int
+	}{
+		{"token", "anilist:21", 21},
+		{"token among words", "see anilist:21 for sequel", 21},
+		{"different tokens", "anilist:21 anilist:22", 0},
+		{"trailing letters", "anilist:21x", 0},
+		{"word prefix", "myanilist:21", 0},
+		{"mal token", "mal:21", 0},
+		{"empty", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := anilistIDFromComments(tt.comments); got != tt.want {
+				t.Errorf("anilistIDFromComments(%q) = %d, want %d", tt.comments, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCommentsMapping(t *testing.T) {
+	srcs := []Source{
+		Anime{IDAnilist: 10, IDMal: 100},
+		Anime{IDAnilist: 11, IDMal: 110, NotesIDMal: 110},
+		Anime{IDAnilist: 12, IDMal: 120},
+		Anime{IDAnilist: 13, IDMal: 130},
+	}
+	tgts := []Target{
+		Anime{IDAnilist: -1, IDMal: 101, CommentsIDAnilist: 10},
+		Anime{IDAnilist: -1, IDMal: 111, CommentsIDAnilist: 11},
+		Anime{IDAnilist: -1, IDMal: 121, CommentsIDAnilist: 12},
+		Anime{IDAnilist: -1, IDMal: 122, CommentsIDAnilist: 12},
+		Anime{IDAnilist: -1, IDMal: 130},
+	}
+
+	got := withCommentsMapping(srcs, tgts)
+	want := []int{101, 110, 120, 130} // claimed, notes token, ambiguous claim, no claim
+	for i, src := range got {
+		if id := src.(Anime).IDMal; id != want[i] {
+			t.Errorf("source %d: got MAL id %d, want %d", i, id, want[i])
+		}
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -214,15 +216,38 @@ func startServer(ctx context.Context, oauth *OAuth, port string, done chan<- boo
 
 	server.Handler = mux
 
+	// Listen before the URL is printed, so a busy port isn't found after the user logs in.
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatalf("Error starting server: %v", listenError(port, err))
+	}
+
 	go func() {
 		log.Printf("Server started at http://localhost:%s", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Error starting server: %v", err)
 		}
 		log.Println("Server stopped")
 	}()
 
 	log.Println("Navigate to the following URL for authorization:", oauth.GetAuthURL())
+
+	if !(*noBrowser) {
+		if err := openBrowser(oauth.GetAuthURL()); err != nil {
+			DPrintf("Error opening browser: %v", err)
+		}
+	}
+}
+
+// listenError explains a busy port. Another port can't be picked automatically,
+// since AniList and MAL accept only the redirect URL from the app settings.
+func listenError(port string, err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("OAuth callback port %s is in use, stop the other process using it "+
+			"(e.g. another running anilist-mal-sync) or set oauth.port (or PORT env) to a free port "+
+			"and change the port in the redirect URL in AniList and MAL app settings: %w", port, err)
+	}
+	return err
 }
 
 func getToken(ctx context.Context, oauth *OAuth, port string) {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestListenErrorBusyPort(t *testing.T) {
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { busy.Close() })
+	port := strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+
+	ln, err := net.Listen("tcp", ":"+port)
+	if err == nil {
+		ln.Close()
+		t.Fatalf("port %s isn't busy", port)
+	}
+
+	got := listenError(port, err)
+	if !errors.Is(got, syscall.EADDRINUSE) {
+		t.Errorf("got error %v, want it to wrap EADDRINUSE", got)
+	}
+	for _, want := range []string{"port " + port + " is in use", "oauth.port"} {
+		if !strings.Contains(got.Error(), want) {
+			t.Errorf("error %q has no %q", got, want)
+		}
+	}
+}
+
+func TestListenErrorOther(t *testing.T) {
+	err := errors.New("permission denied")
+	if got := listenError("80", err); got != err {
+		t.Errorf("got error %v, want it as is", got)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Policies for progress above the known number of episodes or chapters, see checkProgress.
+const (
+	invalidProgressClamp = "clamp"
+	invalidProgressSkip  = "skip"
+	invalidProgressPush  = "push"
+)
+
+func validateInvalidProgressPolicy(policy string) error {
+	switch policy {
+	case "", invalidProgressClamp, invalidProgressSkip, invalidProgressPush:
+		return nil
+	default:
+		return fmt.Errorf("sync.on_invalid_progress: unknown policy %q, known: %s, %s, %s",
+			policy, invalidProgressClamp, invalidProgressSkip, invalidProgressPush)
+	}
+}
+
+// withClampedProgress limits progress of the source by the number of episodes, chapters and volumes
+// if it is known. It reports whether the progress was above it.
+func withClampedProgress(src Source) (Source, bool) {
+	switch v := src.(type) {
+	case Anime:
+		if v.NumEpisodes > 0 && v.Progress > v.NumEpisodes {
+			v.Progress = v.NumEpisodes
+			return v, true
+		}
+	case Manga:
+		var clamped bool
+		if v.Chapters > 0 && v.Progress > v.Chapters {
+			v.Progress, clamped = v.Chapters, true
+		}
+		if v.Volumes > 0 && v.ProgressVolumes > v.Volumes {
+			v.ProgressVolumes, clamped = v.Volumes, true
+		}
+		return v, clamped
+	}
+	return src, false
+}
+
+// checkProgress guards against glitches in source data, e.g. 999 episodes watched of 12.
+// It returns the source to sync and false if the source must be skipped.
+func (u *Updater) checkProgress(src Source) (Source, bool) {
+	if u.OnInvalidProgress == invalidProgressPush || u.OnInvalidProgress == "" {
+		return src, true
+	}
+
+	clamped, invalid := withClampedProgress(src)
+	if !invalid {
+		return src, true
+	}
+
+	if u.OnInvalidProgress == invalidProgressSkip {
+		log.Printf("[%s] Skipping %s: progress is above the number of episodes or chapters", u.Prefix, u.title(src))
+		u.Statistics.AddWarning("invalid progress, skipped: %s", u.title(src))
+		return src, false
+	}
+
+	DPrintf("[%s] Progress of %s is above the number of episodes or chapters, clamped", u.Prefix, u.title(src))
+	u.Statistics.AddWarning("invalid progress, clamped: %s", u.title(src))
+	return clamped, true
+}
+
+// withNormalizedPlanProgress zeroes progress of a planned source, MAL resets or rejects it,
+// so syncing it as is would show the entry as changed on every run.
+// It reports whether the progress was zeroed.
+func withNormalizedPlanProgress(src Source) (Source, bool) {
+	switch v := src.(type) {
+	case Anime:
+		if v.Status == StatusPlanToWatch && v.Progress != 0 {
+			v.Progress = 0
+			return v, true
+		}
+	case Manga:
+		if v.Status == MangaStatusPlanToRead && (v.Progress != 0 || v.ProgressVolumes != 0) {
+			v.Progress, v.ProgressVolumes = 0, 0
+			return v, true
+		}
+	}
+	return src, false
+}
+
+func (u *Updater) normalizePlanProgress(src Source) Source {
+	if !u.NormalizePlanProgress {
+		return src
+	}
+
+	normalized, ok := withNormalizedPlanProgress(src)
+	if ok {
+		log.Printf("[%s] %s is planned but has progress, synced with no progress", u.Prefix, u.title(src))
+	}
+	return normalized
+}
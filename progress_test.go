@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckProgress(t *testing.T) {
+	over := Anime{IDMal: 1, TitleEN: "Frieren", NumEpisodes: 12, Progress: 999, Status: StatusWatching}
+
+	tests := []struct {
+		policy       string
+		wantOK       bool
+		wantProgress int
+		wantWarns    int
+	}{
+		{invalidProgressClamp, true, 12, 1},
+		{invalidProgressSkip, false, 999, 1},
+		{invalidProgressPush, true, 999, 0},
+		{"", true, 999, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			u := &Updater{Prefix: "Anime", Statistics: new(Statistics), OnInvalidProgress: tt.policy}
+			src, ok := u.checkProgress(over)
+			if ok != tt.wantOK {
+				t.Errorf("got ok %t, want %t", ok, tt.wantOK)
+			}
+			if p := src.(Anime).Progress; p != tt.wantProgress {
+				t.Errorf("got progress %d, want %d", p, tt.wantProgress)
+			}
+			if n := len(u.Statistics.Warnings); n != tt.wantWarns {
+				t.Errorf("got %d warnings, want %d", n, tt.wantWarns)
+			}
+		})
+	}
+}
+
+func TestWithClampedProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         Source
+		want        Source
+		wantClamped bool
+	}{
+		{"anime within episodes", Anime{NumEpisodes: 12, Progress: 12}, Anime{NumEpisodes: 12, Progress: 12}, false},
+		{"anime unknown episodes", Anime{Progress: 999}, Anime{Progress: 999}, false},
+		{"anime over episodes", Anime{NumEpisodes: 12, Progress: 13}, Anime{NumEpisodes: 12, Progress: 12}, true},
+		{"manga over chapters", Manga{Chapters: 100, Progress: 150}, Manga{Chapters: 100, Progress: 100}, true},
+		{"manga over volumes", Manga{Volumes: 10, ProgressVolumes: 11}, Manga{Volumes: 10, ProgressVolumes: 10}, true},
+		{"manga ongoing", Manga{Progress: 150, ProgressVolumes: 11}, Manga{Progress: 150, ProgressVolumes: 11}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clamped := withClampedProgress(tt.src)
+			if clamped != tt.wantClamped {
+				t.Errorf("got clamped %t, want %t", clamped, tt.wantClamped)
+			}
+			if sourceProgress(got) != sourceProgress(tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if m, ok := got.(Manga); ok && m.ProgressVolumes != tt.want.(Manga).ProgressVolumes {
+				t.Errorf("got volumes %d, want %d", m.ProgressVolumes, tt.want.(Manga).ProgressVolumes)
+			}
+		})
+	}
+}
+
+func TestNormalizePlanProgress(t *testing.T) {
+	planned := Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusPlanToWatch, Progress: 5, NumEpisodes: 28}
+
+	var updated []Anime
+	u := &Updater{
+		Prefix:                "Anime",
+		Statistics:            new(Statistics),
+		NormalizePlanProgress: true,
+		StrategyOrder:         []string{StrategyID},
+		GetTargetByIDFunc: func(context.Context, TargetID) (Target, error) {
+			return Anime{IDAnilist: -1, IDMal: 1, TitleEN: "Frieren", NumEpisodes: 28}, nil
+		},
+		UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, _ EntryOptions) error {
+			updated = append(updated, src.(Anime))
+			return nil
+		},
+	}
+
+	// Not in MAL list yet, it is added with no progress.
+	u.Update(context.Background(), []Source{planned}, nil)
+	if len(updated) != 1 || updated[0].Progress != 0 {
+		t.Fatalf("got updates %+v, want one with no progress", updated)
+	}
+
+	// MAL has it as sent, so the next run doesn't update it again.
+	updated = nil
+	u.Update(context.Background(), []Source{planned}, []Target{
+		Anime{IDAnilist: -1, IDMal: 1, TitleEN: "Frieren", Status: StatusPlanToWatch, NumEpisodes: 28},
+	})
+	if len(updated) != 0 {
+		t.Errorf("got updates %+v, want none", updated)
+	}
+
+	u.NormalizePlanProgress = false
+	if got := u.normalizePlanProgress(planned).(Anime).Progress; got != 5 {
+		t.Errorf("got progress %d with normalization off, want 5", got)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Restore pushes entries of the backup file to MAL, entries which are the same
+// on MAL are skipped. Entries added to MAL after the backup are kept as is.
+func (a *App) Restore(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading backup: %w", err)
+	}
+
+	var key []byte
+	if a.config.Backup.Key != "" {
+		if key, err = a.config.Backup.key(); err != nil {
+			return err
+		}
+	}
+
+	data, err = decryptBackup(key, data)
+	if err != nil {
+		return err
+	}
+
+	var b backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return fmt.Errorf("error parsing backup: %w", err)
+	}
+
+	log.Printf("Restoring MAL %s list from backup of %s", b.Type, b.Time.Local())
+
+	switch b.Type {
+	case "anime":
+		var animes []Anime
+		if err := json.Unmarshal(b.Entries, &animes); err != nil {
+			return fmt.Errorf("error parsing backup entries: %w", err)
+		}
+		malAnimes, err := a.malAnimes(ctx)
+		if err != nil {
+			return err
+		}
+		u := restoreUpdater(a.animeUpdater)
+		u.Update(ctx, newSourcesFromAnimes(animes), newTargetsFromAnimes(malAnimes))
+		u.Statistics.Print(u.Prefix, a.config.Summary.MaxWarnings)
+	case "manga":
+		var mangas []Manga
+		if err := json.Unmarshal(b.Entries, &mangas); err != nil {
+			return fmt.Errorf("error parsing backup entries: %w", err)
+		}
+		malMangas, err := a.malMangas(ctx)
+		if err != nil {
+			return err
+		}
+		u := restoreUpdater(a.mangaUpdater)
+		u.Update(ctx, newSourcesFromMangas(mangas), newTargetsFromMangas(malMangas))
+		u.Statistics.Print(u.Prefix, a.config.Summary.MaxWarnings)
+	default:
+		return fmt.Errorf("unknown backup type %q", b.Type)
+	}
+
+	return nil
+}
+
+// restoreUpdater returns a copy of the sync updater which pushes every backup entry,
+// entries of the backup are MAL ones, so AniList filters don't apply to them
+// and they are matched only by their MAL IDs.
+func restoreUpdater(u *Updater) *Updater {
+	res := *u
+	res.Statistics = new(Statistics)
+	res.StrategyOrder = []string{StrategyID}
+	res.DowngradePolicy = downgradeAllow
+	res.OnlyNew = false
+	res.Since = time.Time{}
+	res.MinEntryAge = 0
+	res.IgnoreTitles = nil
+	res.IncludeHidden = true
+	res.Filters = FiltersConfig{}
+	res.SeasonWindow = SeasonWindow{}
+	res.NoRegressProgress = false
+	res.FieldAuthority = FieldAuthorityConfig{}
+	res.PreserveScore = false
+	res.NoCreateStatuses = nil
+	res.MergeDuplicates = ""
+	res.FillGapsOnly = false
+	res.NormalizePlanProgress = false
+	res.Create = CreateConfig{}
+	res.ZeroScoreUnset = false
+	res.DatesNoClear = false
+	res.DatesTerminalOnly = false
+	res.OnlyStatusChanges = false
+	return &res
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	updatedAt := time.Now().UTC().Format(time.RFC3339)
+	malEntry := func(id int, title, status string, progress, score int) string {
+		return fmt.Sprintf(`{"node": {"id": %d, "title": %q, "num_episodes": 28},
+			"list_status": {"status": %q, "num_episodes_watched": %d, "score": %d, "updated_at": %q}}`,
+			id, title, status, progress, score, updatedAt)
+	}
+	before := writeFixture(t, "before.json", `{"anime": [`+strings.Join([]string{
+		malEntry(1, "Frieren", "watching", 27, 9),
+		malEntry(2, "Dandadan", "completed", 12, 8),
+		malEntry(3, "Bocchi the Rock!", "watching", 5, 8),
+	}, ",")+`]}`)
+	// Frieren is regressed from completed, Dandadan is removed and only the score of Bocchi is changed.
+	after := writeFixture(t, "after.json", `{"anime": [`+strings.Join([]string{
+		malEntry(1, "Frieren", "completed", 28, 10),
+		malEntry(3, "Bocchi the Rock!", "watching", 5, 6),
+	}, ",")+`]}`)
+
+	oldTgt := *targetFile
+	t.Cleanup(func() { *targetFile = oldTgt })
+
+	var restored []string
+	a := &App{
+		config: Config{Backup: BackupConfig{Enabled: true, MaxFiles: 1}},
+		animeUpdater: &Updater{
+			Prefix:          "Anime",
+			Statistics:      new(Statistics),
+			StrategyOrder:   []string{StrategyTitle},
+			DowngradePolicy: downgradeSkip,
+			OnlyNew:         true,
+			Since:           time.Now().Add(time.Hour),
+			MinEntryAge:     24 * time.Hour,
+			EntryOptions:    EntryOptions{OnlyStatusChanges: true},
+			GetTargetByIDFunc: func(_ context.Context, id TargetID) (Target, error) {
+				return Anime{IDAnilist: -1, IDMal: int(id), TitleEN: "Dandadan", NumEpisodes: 12}, nil
+			},
+			GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+				t.Error("backup entry is searched by title")
+				return nil, nil
+			},
+			UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, src Source, _ EntryOptions) error {
+				a := src.(Anime)
+				restored = append(restored, fmt.Sprintf("%d:%s:%d:%v", id, a.Status, a.Progress, a.Score))
+				return nil
+			},
+		},
+		datesLocation: time.UTC,
+	}
+
+	*targetFile = before
+	entries, err := a.malAnimes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := a.config.Backup.writeBackup(t.TempDir(), "anime", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*targetFile = after
+	if err := a.Restore(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(restored)
+	want := "[1:watching:27:9 2:completed:12:8 3:watching:5:8]"
+	if got := fmt.Sprint(restored); got != want {
+		t.Errorf("got restored %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// errRetryBudgetExhausted stops the sync once the run has used all retries of http.retry_budget.
+var errRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// httpRetryDelays are waits before retries of a failed request.
+var httpRetryDelays = []time.Duration{time.Second, 5 * time.Second}
+
+type HTTPConfig struct {
+	RetryBudget int `yaml:"retry_budget"` // retries of the whole run, 0 is unlimited
+}
+
+// retryBudget limits retries of the whole run, so a long outage doesn't turn into
+// a retry storm. It is shared by all clients of the run, nil budget is unlimited.
+type retryBudget struct {
+	mu     sync.Mutex
+	max    int
+	used   int
+	denied bool // a retry was asked for after the budget was used up
+}
+
+func newRetryBudget(max int) *retryBudget {
+	if max == 0 {
+		return nil
+	}
+	return &retryBudget{max: max}
+}
+
+// take reports whether one more retry is allowed and counts it.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.max {
+		b.denied = true
+		return false
+	}
+	b.used++
+	return true
+}
+
+// exhausted reports whether a request failed because no retries were left,
+// the sync is stopped then.
+func (b *retryBudget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.denied
+}
+
+// retryTransport retries requests failed with network errors, 429 and 5xx statuses
+// while the retry budget lasts.
+type retryTransport struct {
+	base   http.RoundTripper
+	budget *retryBudget
+}
+
+// withRetries makes OAuth and API clients created with the context retry failed requests.
+// It wraps the transport already set in the context, e.g. by -dump-http, so every attempt is dumped.
+func withRetries(ctx context.Context, budget *retryBudget) context.Context {
+	base := http.DefaultTransport
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && c.Transport != nil {
+		base = c.Transport
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: &retryTransport{base: base, budget: budget},
+	})
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if !isRetryable(resp, err) || attempt == len(httpRetryDelays) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil { // the body is read and can't be sent again
+			return resp, err
+		}
+
+		if err == nil {
+			err = fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+			resp.Body.Close()
+		}
+		if !t.budget.take() {
+			return nil, fmt.Errorf("%w: %w", errRetryBudgetExhausted, err)
+		}
+
+		DPrintf("Request failed, retrying: %v", err)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(httpRetryDelays[attempt]):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
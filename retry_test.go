@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func retryClient(t *testing.T, budget *retryBudget) *http.Client {
+	t.Helper()
+	delays := httpRetryDelays
+	httpRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	t.Cleanup(func() { httpRetryDelays = delays })
+
+	return withRetries(context.Background(), budget).Value(oauth2.HTTPClient).(*http.Client)
+}
+
+func TestRetryTransportBudgetExhausted(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	budget := newRetryBudget(1)
+	client := retryClient(t, budget)
+
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, errRetryBudgetExhausted)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2: the request and its only retry", calls)
+	}
+	if !budget.exhausted() {
+		t.Error("budget isn't exhausted")
+	}
+
+	// Once exhausted, requests fail without retries.
+	calls = 0
+	if _, err := client.Get(srv.URL); !errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, errRetryBudgetExhausted)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
+
+func TestRetryTransportResendsBody(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	resp, err := retryClient(t, nil).Post(srv.URL, "application/json", strings.NewReader(`{"query":"{}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want 200", resp.StatusCode)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"query":"{}"}` {
+		t.Errorf("got bodies %q, want the body sent twice", bodies)
+	}
+}
+
+func TestRetryTransportKeepsClientErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	resp, err := retryClient(t, nil).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound || calls != 1 {
+		t.Errorf("got status %d after %d calls, want 404 after 1", resp.StatusCode, calls)
+	}
+}
+
+func TestSearchRetriesTakeBudget(t *testing.T) {
+	delays := searchRetryDelays
+	searchRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	t.Cleanup(func() { searchRetryDelays = delays })
+
+	var calls int
+	u := &Updater{
+		Prefix:      "Anime",
+		Statistics:  new(Statistics),
+		RetryBudget: newRetryBudget(1),
+		GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+			calls++
+			return nil, &SyncError{Category: ErrorCategoryServer, Err: errors.New("502 bad gateway")}
+		},
+	}
+
+	_, err := u.searchTargets(context.Background(), "Frieren")
+	if !errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, errRetryBudgetExhausted)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2: the search and its only retry", calls)
+	}
+}
+
+func TestUpdaterStopsOnExhaustedBudget(t *testing.T) {
+	budget := newRetryBudget(1)
+	budget.take()
+	budget.take()
+
+	var calls int
+	u := &Updater{
+		Prefix:      "Anime",
+		Statistics:  new(Statistics),
+		RetryBudget: budget,
+		GetTargetByIDFunc: func(context.Context, TargetID) (Target, error) {
+			calls++
+			return nil, errTargetNotFound
+		},
+	}
+	u.Update(context.Background(), []Source{
+		Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching},
+	}, nil)
+
+	if calls != 0 {
+		t.Errorf("got %d lookups after the budget was exhausted, want 0", calls)
+	}
+}
+
+func TestRetryBudgetUnlimited(t *testing.T) {
+	b := newRetryBudget(0)
+	for i := 0; i < 1000; i++ {
+		if !b.take() {
+			t.Fatalf("retry %d denied by unlimited budget", i)
+		}
+	}
+	if b.exhausted() {
+		t.Error("unlimited budget is exhausted")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/rl404/verniy"
+)
+
+var scoreFormats = []verniy.ScoreFormat{
+	verniy.ScoreFormatPoint100,
+	verniy.ScoreFormatPoint100Decimal,
+	verniy.ScoreFormatPoint10,
+	verniy.ScoreFormatPoint5,
+	verniy.ScoreFormatPoint3,
+}
+
+func validateScoreFormat(format string) error {
+	if format == "" || slices.Contains(scoreFormats, verniy.ScoreFormat(format)) {
+		return nil
+	}
+	return fmt.Errorf("score.format_override: unknown format %q, known: %v", format, scoreFormats)
+}
+
+// scoreFormatMax returns the highest AniList score in the format.
+func scoreFormatMax(format verniy.ScoreFormat) float64 {
+	switch format {
+	case verniy.ScoreFormatPoint100:
+		return 100
+	case verniy.ScoreFormatPoint5:
+		return 5
+	case verniy.ScoreFormatPoint3:
+		return 3
+	default:
+		return 10
+	}
+}
+
+// normalizeScoreForMAL converts AniList score in the user's format to MAL 0-10 scale.
+// Scores out of the format range are clamped, so broken source data isn't pushed to MAL.
+func normalizeScoreForMAL(score float64, format verniy.ScoreFormat) float64 {
+	score = math.Max(0, math.Min(score, scoreFormatMax(format)))
+	switch format {
+	case verniy.ScoreFormatPoint100:
+		score /= 10
+	case verniy.ScoreFormatPoint5:
+		score *= 2
+	case verniy.ScoreFormatPoint3:
+		score = score * 10 / 3
+	}
+	return math.Min(math.Round(score), 10)
+}
+
+const (
+	scoreRoundTripLossy    = "lossy"
+	scoreRoundTripPreserve = "preserve"
+)
+
+func validateScoreRoundTripPolicy(policy string) error {
+	switch policy {
+	case "", scoreRoundTripLossy, scoreRoundTripPreserve:
+		return nil
+	default:
+		return fmt.Errorf("score.round_trip_policy: unknown policy %q, known: %s, %s", policy, scoreRoundTripLossy, scoreRoundTripPreserve)
+	}
+}
+
+// denormalizeMALScore converts MAL 0-10 score to AniList score in the user's format.
+func denormalizeMALScore(score float64, format verniy.ScoreFormat) float64 {
+	switch format {
+	case verniy.ScoreFormatPoint100:
+		return score * 10
+	case verniy.ScoreFormatPoint5:
+		return score / 2
+	case verniy.ScoreFormatPoint3:
+		return score * 3 / 10
+	default:
+		return score
+	}
+}
+
+// scoreRoundTripTolerance is the max rounding error of conversion between
+// AniList format and MAL scale, in AniList format units.
+func scoreRoundTripTolerance(format verniy.ScoreFormat) float64 {
+	switch format {
+	case verniy.ScoreFormatPoint100:
+		return 5 // half of MAL point
+	default:
+		return 0.5 // half of MAL point or of AniList point, whichever is coarser
+	}
+}
+
+// isScoreRoundingOf reports whether MAL score differs from AniList score only
+// by rounding of format conversion, e.g. 85 on AniList and 8 on MAL.
+// Not rated entries are never a rounding of rated ones.
+func isScoreRoundingOf(malScore, anilistScore float64, format verniy.ScoreFormat) bool {
+	if malScore == 0 || anilistScore == 0 {
+		return malScore == anilistScore
+	}
+	return math.Abs(denormalizeMALScore(malScore, format)-anilistScore) <= scoreRoundTripTolerance(format)
+}
+
+// withPreservedScore keeps the target score if the source score is only its rounding,
+// so the target isn't updated back and forth on rounding.
+func withPreservedScore(src Source, tgt Target, format verniy.ScoreFormat) Source {
+	switch a := src.(type) {
+	case Anime:
+		if b, ok := tgt.(Anime); ok && isScoreRoundingOf(b.Score, a.AnilistScore, format) {
+			a.Score = b.Score
+		}
+		return a
+	case Manga:
+		if b, ok := tgt.(Manga); ok && isScoreRoundingOf(b.Score, a.AnilistScore, format) {
+			a.Score = b.Score
+		}
+		return a
+	default:
+		return src
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rl404/verniy"
+)
+
+func TestNormalizeScoreForMAL(t *testing.T) {
+	tests := []struct {
+		score  float64
+		format verniy.ScoreFormat
+		want   float64
+	}{
+		{85, verniy.ScoreFormatPoint100, 9},
+		{7.5, verniy.ScoreFormatPoint100Decimal, 8},
+		{7, verniy.ScoreFormatPoint10, 7},
+		{4, verniy.ScoreFormatPoint5, 8},
+		{2, verniy.ScoreFormatPoint3, 7},
+		{3, verniy.ScoreFormatPoint3, 10},
+		{0, verniy.ScoreFormatPoint5, 0},
+		{150, verniy.ScoreFormatPoint100, 10},
+		{-1, verniy.ScoreFormatPoint10, 0},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeScoreForMAL(tt.score, tt.format); got != tt.want {
+			t.Errorf("normalizeScoreForMAL(%v, %s) = %v, want %v", tt.score, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestScoreFormatDetected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "scoreFormat") || !strings.Contains(string(body), `"name":"someone"`) {
+			t.Errorf("unexpected query: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"data":{"User":{"mediaListOptions":{"scoreFormat":"POINT_5"}}}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	v := verniy.New()
+	v.Host = srv.URL
+	c := &AnilistClient{c: v, username: "someone"}
+
+	format, err := c.GetScoreFormat(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if format != verniy.ScoreFormatPoint5 {
+		t.Fatalf("got format %s, want %s", format, verniy.ScoreFormatPoint5)
+	}
+	if got := normalizeScoreForMAL(4, format); got != 8 {
+		t.Errorf("4 of 5 is normalized to %v, want 8", got)
+	}
+}
+
+func TestIsScoreRoundingOf(t *testing.T) {
+	tests := []struct {
+		mal, anilist float64
+		format       verniy.ScoreFormat
+		want         bool
+	}{
+		{9, 85, verniy.ScoreFormatPoint100, true},
+		{8, 85, verniy.ScoreFormatPoint100, true},
+		{7, 85, verniy.ScoreFormatPoint100, false},
+		{8, 4, verniy.ScoreFormatPoint5, true},
+		{9, 4, verniy.ScoreFormatPoint5, true},
+		{10, 4, verniy.ScoreFormatPoint5, false},
+		{8, 7.5, verniy.ScoreFormatPoint100Decimal, true},
+		{7, 2, verniy.ScoreFormatPoint3, true},
+		{0, 85, verniy.ScoreFormatPoint100, false},
+		{9, 0, verniy.ScoreFormatPoint100, false},
+		{0, 0, verniy.ScoreFormatPoint100, true},
+	}
+
+	for _, tt := range tests {
+		if got := isScoreRoundingOf(tt.mal, tt.anilist, tt.format); got != tt.want {
+			t.Errorf("isScoreRoundingOf(%v, %v, %s) = %t, want %t", tt.mal, tt.anilist, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPreserveScoreNoSpuriousUpdate(t *testing.T) {
+	// 85 on AniList is 9 on MAL, the user set 8 there, which is a rounding of 85 too.
+	src := Anime{IDAnilist: 1, IDMal: 1, Status: StatusCompleted, Progress: 12, NumEpisodes: 12,
+		AnilistScore: 85, Score: normalizeScoreForMAL(85, verniy.ScoreFormatPoint100)}
+	tgt := Anime{IDAnilist: -1, IDMal: 1, Status: StatusCompleted, Progress: 12, NumEpisodes: 12, Score: 8}
+
+	for _, tt := range []struct {
+		preserve    bool
+		wantUpdates int
+	}{{false, 1}, {true, 0}} {
+		var updates int
+		u := &Updater{
+			Prefix:        "Anime",
+			Statistics:    new(Statistics),
+			PreserveScore: tt.preserve,
+			ScoreFormat:   verniy.ScoreFormatPoint100,
+			UpdateTargetBySourceFunc: func(context.Context, TargetID, Source, EntryOptions) error {
+				updates++
+				return nil
+			},
+		}
+		u.Update(context.Background(), []Source{src}, []Target{tgt})
+		if updates != tt.wantUpdates {
+			t.Errorf("preserve %t: got %d updates, want %d", tt.preserve, updates, tt.wantUpdates)
+		}
+	}
+}
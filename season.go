@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var seasons = []string{"winter", "spring", "summer", "fall"}
+
+type season struct {
+	year  int
+	index int
+}
+
+func (s season) before(o season) bool {
+	if s.year != o.year {
+		return s.year < o.year
+	}
+	return s.index < o.index
+}
+
+// parseSeason parses season in format "2024-fall".
+func parseSeason(s string) (season, error) {
+	yearStr, name, ok := strings.Cut(s, "-")
+	if !ok {
+		return season{}, fmt.Errorf("invalid season %q, expected format is 2024-fall", s)
+	}
+
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		return season{}, fmt.Errorf("invalid season year %q: %w", s, err)
+	}
+
+	index := slices.Index(seasons, strings.ToLower(name))
+	if index < 0 {
+		return season{}, fmt.Errorf("invalid season name %q, known: %v", s, seasons)
+	}
+
+	return season{year: year, index: index}, nil
+}
+
+// SeasonWindow limits the anime sync to entries aired between start and end seasons inclusively.
+type SeasonWindow struct {
+	start, end *season
+}
+
+func newSeasonWindow(start, end string) (SeasonWindow, error) {
+	var w SeasonWindow
+	if start != "" {
+		s, err := parseSeason(start)
+		if err != nil {
+			return SeasonWindow{}, fmt.Errorf("start season: %w", err)
+		}
+		w.start = &s
+	}
+	if end != "" {
+		s, err := parseSeason(end)
+		if err != nil {
+			return SeasonWindow{}, fmt.Errorf("end season: %w", err)
+		}
+		w.end = &s
+	}
+	if w.start != nil && w.end != nil && w.end.before(*w.start) {
+		return SeasonWindow{}, fmt.Errorf("end season %s is before start season %s", end, start)
+	}
+	return w, nil
+}
+
+// contains reports whether the source is inside the window.
+// Manga has no seasons and is always inside, anime with unknown year is always outside.
+// Anime with known year but unknown season is compared by year only.
+func (w SeasonWindow) contains(src Source) bool {
+	if w.start == nil && w.end == nil {
+		return true
+	}
+
+	a, ok := src.(Anime)
+	if !ok {
+		return true
+	}
+
+	if a.SeasonYear == 0 {
+		return false
+	}
+
+	index := slices.Index(seasons, a.Season)
+	if index < 0 {
+		if w.start != nil && a.SeasonYear < w.start.year {
+			return false
+		}
+		if w.end != nil && a.SeasonYear > w.end.year {
+			return false
+		}
+		return true
+	}
+
+	s := season{year: a.SeasonYear, index: index}
+	if w.start != nil && s.before(*w.start) {
+		return false
+	}
+	if w.end != nil && w.end.before(s) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestPartialSeasons(t *testing.T) {
+	window, err := newSeasonWindow("2023-fall", "2024-spring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MAL entry of another year, the year guard rejects it only if the source year is known.
+	other := Anime{IDMal: 99, SeasonYear: 2015}
+
+	tests := []struct {
+		name       string
+		media      string
+		wantYear   int
+		wantSeason string
+		inWindow   bool
+		yearsClose bool
+	}{
+		{"both", `"seasonYear": 2023, "season": "FALL"`, 2023, "fall", true, false},
+		{"both, outside window", `"seasonYear": 2023, "season": "SUMMER"`, 2023, "summer", false, false},
+		{"year only", `"seasonYear": 2024`, 2024, "", true, false},
+		{"year only, outside window", `"seasonYear": 2022`, 2022, "", false, false},
+		{"season only", `"season": "FALL"`, 0, "fall", false, true},
+		{"neither", ``, 0, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media := `{"id": 1, "idMal": 1, "title": {"romaji": "Frieren"}`
+			if tt.media != "" {
+				media += ", " + tt.media
+			}
+			a := animeFromAnilist(t, `{"status": "CURRENT", "media": `+media+`}}`)
+
+			if a.SeasonYear != tt.wantYear || a.Season != tt.wantSeason {
+				t.Errorf("got year %d and season %q, want %d and %q", a.SeasonYear, a.Season, tt.wantYear, tt.wantSeason)
+			}
+			if got := window.contains(a); got != tt.inWindow {
+				t.Errorf("contains() = %t, want %t", got, tt.inWindow)
+			}
+			if got := seasonYearsClose(a, other, 1); got != tt.yearsClose {
+				t.Errorf("seasonYearsClose() = %t, want %t", got, tt.yearsClose)
+			}
+		})
+	}
+}
@@ -1,14 +1,132 @@
 package main
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
 
 type Statistics struct {
 	UpdatedCount int
 	SkippedCount int
 	TotalCount   int
+	Warnings     []string
+	Errors       map[ErrorCategory]int
+	Items        []StatisticsItem
 }
 
-func (s Statistics) Print(prefix string) {
+// StatisticsItem is the outcome of a single processed entry.
+type StatisticsItem struct {
+	Type     string   `json:"type"` // anime or manga
+	Title    string   `json:"title"`
+	SourceID int      `json:"anilist_id"`
+	TargetID TargetID `json:"mal_id"`
+	Action   string   `json:"action"` // updated, skipped, dry-run or error
+	Reason   string   `json:"reason"` // skip reason, error or diff
+}
+
+const reasonDryRun = "dry run"
+
+// addItem records the outcome of the entry from the final event of its processing.
+func (s *Statistics) addItem(prefix string, e SyncEvent) {
+	item := StatisticsItem{
+		Type:     strings.ToLower(prefix),
+		Title:    e.Title,
+		SourceID: e.SourceID,
+		TargetID: e.TargetID,
+	}
+
+	switch e.Kind {
+	case SyncEventUpdated:
+		item.Action, item.Reason = "updated", e.Diff
+	case SyncEventSkipped:
+		item.Action, item.Reason = "skipped", e.Reason
+		if e.Reason == reasonDryRun {
+			item.Action, item.Reason = "dry-run", e.Diff
+		}
+	case SyncEventError:
+		item.Action = "error"
+		if e.Err != nil {
+			item.Reason = e.Err.Error()
+		}
+	default:
+		return
+	}
+
+	s.Items = append(s.Items, item)
+}
+
+func (s *Statistics) AddWarning(format string, v ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, v...))
+}
+
+// AddError counts the error by its category.
+func (s *Statistics) AddError(err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[ErrorCategory]int)
+	}
+	s.Errors[classifyError(err)]++
+}
+
+func (s Statistics) HasWarnings() bool {
+	return len(s.Warnings) > 0
+}
+
+// Print logs the counts and up to maxLines warnings, errors and skip reasons each, 0 prints all of them.
+func (s Statistics) Print(prefix string, maxLines int) {
 	log.Printf("[%s] Updated %d out of %d\n", prefix, s.UpdatedCount, s.TotalCount)
 	log.Printf("[%s] Skipped %d\n", prefix, s.SkippedCount)
+	categories := make([]ErrorCategory, 0, len(s.Errors))
+	for c := range s.Errors {
+		categories = append(categories, c)
+	}
+	slices.Sort(categories)
+	for _, c := range categories {
+		log.Printf("[%s] Errors %s: %d\n", prefix, c, s.Errors[c])
+	}
+
+	warnings := make([]string, 0, len(s.Warnings))
+	for _, w := range s.Warnings {
+		warnings = append(warnings, "Warning: "+w)
+	}
+	printLines(prefix, "warnings", warnings, maxLines)
+
+	var errs []string
+	skips := make(map[string]int)
+	for _, item := range s.Items {
+		switch item.Action {
+		case "error":
+			errs = append(errs, fmt.Sprintf("Error: %s: %s", item.Title, item.Reason))
+		case "skipped":
+			skips[item.Reason]++
+		}
+	}
+	printLines(prefix, "errors", errs, maxLines)
+
+	reasons := make([]string, 0, len(skips))
+	for r := range skips {
+		reasons = append(reasons, r)
+	}
+	slices.SortFunc(reasons, func(a, b string) int {
+		if skips[a] != skips[b] {
+			return skips[b] - skips[a]
+		}
+		return strings.Compare(a, b)
+	})
+	for i, r := range reasons {
+		reasons[i] = fmt.Sprintf("Skipped %s: %d", r, skips[r])
+	}
+	printLines(prefix, "skip reasons", reasons, maxLines)
+}
+
+// printLines logs up to maxLines lines, 0 logs all of them, and the number of the rest.
+func printLines(prefix, what string, lines []string, maxLines int) {
+	for i, line := range lines {
+		if maxLines > 0 && i == maxLines {
+			log.Printf("[%s] ... and %d more %s (see -summary-json)\n", prefix, len(lines)-maxLines, what)
+			return
+		}
+		log.Printf("[%s] %s\n", prefix, line)
+	}
 }
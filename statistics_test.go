@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStatisticsPrintCaps(t *testing.T) {
+	var s Statistics
+	for i := 0; i < 5; i++ {
+		s.AddWarning("warning %d", i)
+		s.Items = append(s.Items,
+			StatisticsItem{Title: fmt.Sprintf("broken %d", i), Action: "error", Reason: "502"},
+			StatisticsItem{Title: fmt.Sprintf("hidden %d", i), Action: "skipped", Reason: fmt.Sprintf("reason %d", i)},
+		)
+	}
+
+	var out strings.Builder
+	w, flags := log.Writer(), log.Flags()
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	})
+
+	s.Print("Anime", 2)
+
+	got := out.String()
+	for _, want := range []string{
+		"Warning: warning 1",
+		"... and 3 more warnings (see -summary-json)",
+		"Error: broken 1: 502",
+		"... and 3 more errors (see -summary-json)",
+		"Skipped reason 1: 1",
+		"... and 3 more skip reasons (see -summary-json)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output has no %q:\n%s", want, got)
+		}
+	}
+	for _, capped := range []string{"warning 2", "broken 2", "reason 2"} {
+		if strings.Contains(got, capped) {
+			t.Errorf("output has %q above the cap:\n%s", capped, got)
+		}
+	}
+
+	out.Reset()
+	s.Print("Anime", 0)
+	if got := out.String(); !strings.Contains(got, "warning 4") || strings.Contains(got, "more") {
+		t.Errorf("0 doesn't print all:\n%s", got)
+	}
+}
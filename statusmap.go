@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rl404/verniy"
+)
+
+var anilistStatuses = []verniy.MediaListStatus{
+	verniy.MediaListStatusCurrent,
+	verniy.MediaListStatusPlanning,
+	verniy.MediaListStatusCompleted,
+	verniy.MediaListStatusDropped,
+	verniy.MediaListStatusPaused,
+	verniy.MediaListStatusRepeating,
+}
+
+// statusMapAnime and statusMapManga convert status_map values to statuses,
+// values of both anime and manga are accepted for any type.
+var (
+	statusMapAnime = map[string]Status{
+		"watching":      StatusWatching,
+		"reading":       StatusWatching,
+		"completed":     StatusCompleted,
+		"on_hold":       StatusOnHold,
+		"dropped":       StatusDropped,
+		"plan_to_watch": StatusPlanToWatch,
+		"plan_to_read":  StatusPlanToWatch,
+	}
+	statusMapManga = map[string]MangaStatus{
+		"watching":      MangaStatusReading,
+		"reading":       MangaStatusReading,
+		"completed":     MangaStatusCompleted,
+		"on_hold":       MangaStatusOnHold,
+		"dropped":       MangaStatusDropped,
+		"plan_to_watch": MangaStatusPlanToRead,
+		"plan_to_read":  MangaStatusPlanToRead,
+	}
+)
+
+// StatusMap overrides MAL status for AniList statuses, e.g. PAUSED: dropped.
+// Statuses which are not in the map keep the default mapping.
+type StatusMap map[string]string
+
+func (m StatusMap) validate() error {
+	for k, v := range m {
+		if !slices.Contains(anilistStatuses, verniy.MediaListStatus(k)) {
+			return fmt.Errorf("status_map: unknown AniList status %q, known: %v", k, anilistStatuses)
+		}
+		if _, ok := statusMapAnime[v]; !ok {
+			return fmt.Errorf("status_map: unknown MAL status %q for %s", v, k)
+		}
+	}
+	return nil
+}
+
+// defaultStatuses returns AniList statuses missing in the map, they keep the default mapping.
+func (m StatusMap) defaultStatuses() []verniy.MediaListStatus {
+	var res []verniy.MediaListStatus
+	for _, s := range anilistStatuses {
+		if _, ok := m[string(s)]; !ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (m StatusMap) animeStatus(s verniy.MediaListStatus) (Status, bool) {
+	v, ok := m[string(s)]
+	if !ok {
+		return "", false
+	}
+	return statusMapAnime[v], true
+}
+
+func (m StatusMap) mangaStatus(s verniy.MediaListStatus) (MangaStatus, bool) {
+	v, ok := m[string(s)]
+	if !ok {
+		return "", false
+	}
+	return statusMapManga[v], true
+}
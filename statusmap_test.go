@@ -0,0 +1,57 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rl404/verniy"
+)
+
+func TestStatusMapPausedToDropped(t *testing.T) {
+	m := StatusMap{"PAUSED": "dropped"}
+	if err := m.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if st, ok := m.animeStatus(verniy.MediaListStatusPaused); !ok || st != StatusDropped {
+		t.Errorf("anime PAUSED is %q, want %q", st, StatusDropped)
+	}
+	if st, ok := m.mangaStatus(verniy.MediaListStatusPaused); !ok || st != MangaStatusDropped {
+		t.Errorf("manga PAUSED is %q, want %q", st, MangaStatusDropped)
+	}
+	if _, ok := m.animeStatus(verniy.MediaListStatusCurrent); ok {
+		t.Error("CURRENT is mapped, want the default mapping")
+	}
+
+	want := []verniy.MediaListStatus{
+		verniy.MediaListStatusCurrent,
+		verniy.MediaListStatusPlanning,
+		verniy.MediaListStatusCompleted,
+		verniy.MediaListStatusDropped,
+		verniy.MediaListStatusRepeating,
+	}
+	if got := m.defaultStatuses(); !slices.Equal(got, want) {
+		t.Errorf("default statuses are %v, want %v", got, want)
+	}
+}
+
+func TestStatusMapValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       StatusMap
+		wantErr bool
+	}{
+		{"empty", StatusMap{}, false},
+		{"manga value", StatusMap{"CURRENT": "reading"}, false},
+		{"unknown anilist status", StatusMap{"ON_HOLD": "dropped"}, true},
+		{"unknown mal status", StatusMap{"PAUSED": "paused"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
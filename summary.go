@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+)
+
+// summaryItems are entries processed by the updaters.
+type summaryItems []StatisticsItem
+
+func (summaryItems) Header() []string {
+	return []string{"type", "title", "anilist_id", "mal_id", "action", "reason"}
+}
+
+func (items summaryItems) Rows() [][]string {
+	res := make([][]string, 0, len(items))
+	for _, item := range items {
+		res = append(res, []string{
+			item.Type,
+			item.Title,
+			strconv.Itoa(item.SourceID),
+			strconv.Itoa(int(item.TargetID)),
+			item.Action,
+			item.Reason,
+		})
+	}
+	return res
+}
+
+// writeSummaryCSV writes a row per processed entry, e.g. to review a big sync in a spreadsheet.
+func writeSummaryCSV(path string, stats ...*Statistics) error {
+	var items summaryItems
+	for _, s := range stats {
+		items = append(items, s.Items...)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := (csvFormatter{}).Format(f, items); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeSummaryJSON writes the whole summary with all warnings, errors and skip reasons,
+// the same one the post sync hook gets.
+func writeSummaryJSON(path string, sum SyncSummary) error {
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
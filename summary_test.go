@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSummaryCSV(t *testing.T) {
+	anime := new(Statistics)
+	anime.addItem("Anime", SyncEvent{Kind: SyncEventUpdated, Title: "Frieren", SourceID: 154587, TargetID: 52991, Diff: "Progress: 27 -> 28"})
+	anime.addItem("Anime", SyncEvent{Kind: SyncEventSkipped, Title: "Dandadan", SourceID: 171018, TargetID: 57334, Reason: reasonDryRun, Diff: "Score: 0 -> 9"})
+	manga := new(Statistics)
+	manga.addItem("Manga", SyncEvent{Kind: SyncEventSkipped, Title: "Berserk", SourceID: 30002, TargetID: 2, Reason: "no changes"})
+	manga.addItem("Manga", SyncEvent{Kind: SyncEventError, Title: "Vagabond", SourceID: 30656, Err: errors.New("502 bad gateway")})
+	anime.addItem("Anime", SyncEvent{Kind: SyncEventMatched, Title: "not an outcome"})
+
+	path := filepath.Join(t.TempDir(), "summary.csv")
+	if err := writeSummaryCSV(path, manga, anime); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `type,title,anilist_id,mal_id,action,reason
+manga,Berserk,30002,2,skipped,no changes
+manga,Vagabond,30656,0,error,502 bad gateway
+anime,Frieren,154587,52991,updated,Progress: 27 -> 28
+anime,Dandadan,171018,57334,dry-run,Score: 0 -> 9
+`
+	if string(got) != want {
+		t.Errorf("got CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
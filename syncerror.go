@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/nstratos/go-myanimelist/mal"
+)
+
+type ErrorCategory string
+
+const (
+	ErrorCategoryAuth       ErrorCategory = "auth"
+	ErrorCategoryRateLimit  ErrorCategory = "rate-limit"
+	ErrorCategoryNotFound   ErrorCategory = "not-found"
+	ErrorCategoryValidation ErrorCategory = "validation"
+	ErrorCategoryNetwork    ErrorCategory = "network"
+	ErrorCategoryServer     ErrorCategory = "server"
+	ErrorCategoryUnknown    ErrorCategory = "unknown"
+)
+
+// SyncError is an API error with a category to group failures by.
+type SyncError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *SyncError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Category, e.Err)
+}
+
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+// newSyncError wraps API error with its category, it keeps nil and already wrapped errors as is.
+func newSyncError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) {
+		return err
+	}
+	return &SyncError{Category: classifyError(err), Err: err}
+}
+
+func classifyError(err error) ErrorCategory {
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) {
+		return syncErr.Category
+	}
+
+	var malErr *mal.ErrorResponse
+	if errors.As(err, &malErr) && malErr.Response != nil {
+		return classifyStatusCode(malErr.Response.StatusCode)
+	}
+
+	var anilistErr anilistErrorResponse
+	if errors.As(err, &anilistErr) && len(anilistErr.Errors) > 0 {
+		return classifyStatusCode(anilistErr.Errors[0].Status)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorCategoryNetwork
+	}
+
+	return ErrorCategoryUnknown
+}
+
+func classifyStatusCode(code int) ErrorCategory {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorCategoryAuth
+	case http.StatusTooManyRequests:
+		return ErrorCategoryRateLimit
+	case http.StatusNotFound:
+		return ErrorCategoryNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrorCategoryValidation
+	default:
+		if code >= http.StatusInternalServerError {
+			return ErrorCategoryServer
+		}
+		return ErrorCategoryUnknown
+	}
+}
+
+// isTransient reports whether the request may succeed if it is repeated.
+func (c ErrorCategory) isTransient() bool {
+	return c == ErrorCategoryNetwork || c == ErrorCategoryRateLimit || c == ErrorCategoryServer
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nstratos/go-myanimelist/mal"
+)
+
+func malError(code int) error {
+	return &mal.ErrorResponse{Response: &http.Response{StatusCode: code}, Message: http.StatusText(code)}
+}
+
+func anilistError(status int, msg string) error {
+	var resp anilistErrorResponse
+	resp.Errors = append(resp.Errors, struct {
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+	}{msg, status})
+	return resp
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{"mal unauthorized", malError(http.StatusUnauthorized), ErrorCategoryAuth},
+		{"mal forbidden", malError(http.StatusForbidden), ErrorCategoryAuth},
+		{"mal not found", malError(http.StatusNotFound), ErrorCategoryNotFound},
+		{"mal bad request", malError(http.StatusBadRequest), ErrorCategoryValidation},
+		{"mal too many requests", malError(http.StatusTooManyRequests), ErrorCategoryRateLimit},
+		{"mal bad gateway", malError(http.StatusBadGateway), ErrorCategoryServer},
+		{"wrapped mal error", fmt.Errorf("error updating anime: %w", malError(http.StatusNotFound)), ErrorCategoryNotFound},
+		{"anilist rate limit", anilistError(http.StatusTooManyRequests, "Too Many Requests."), ErrorCategoryRateLimit},
+		{"anilist invalid token", anilistError(http.StatusBadRequest, "Invalid token"), ErrorCategoryValidation},
+		{"anilist server error", anilistError(http.StatusInternalServerError, "Internal Server Error"), ErrorCategoryServer},
+		{"network timeout", fmt.Errorf("get list: %w", timeoutError{}), ErrorCategoryNetwork},
+		{"sync error", &SyncError{Category: ErrorCategoryAuth, Err: errors.New("token expired")}, ErrorCategoryAuth},
+		{"plain error", errors.New("something broke"), ErrorCategoryUnknown},
+		{"context canceled", context.Canceled, ErrorCategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSyncError(t *testing.T) {
+	if newSyncError(nil) != nil {
+		t.Error("nil error is wrapped")
+	}
+
+	err := newSyncError(malError(http.StatusNotFound))
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) || syncErr.Category != ErrorCategoryNotFound {
+		t.Fatalf("got %v, want not-found SyncError", err)
+	}
+	if again := newSyncError(err); again != err {
+		t.Errorf("wrapped error is wrapped again: %v", again)
+	}
+}
+
+func TestWrapMalErrorNotAccessible(t *testing.T) {
+	tests := []struct {
+		code          int
+		want          ErrorCategory
+		notAccessible bool
+	}{
+		{http.StatusNotFound, ErrorCategoryNotFound, true},
+		{http.StatusForbidden, ErrorCategoryAuth, false},
+		{http.StatusInternalServerError, ErrorCategoryServer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			err := wrapMalError(malError(tt.code))
+			if got := classifyError(err); got != tt.want {
+				t.Errorf("got category %s, want %s", got, tt.want)
+			}
+			if got := errors.Is(err, errMalNotAccessible); got != tt.notAccessible {
+				t.Errorf("not accessible is %t, want %t", got, tt.notAccessible)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var titleSymbolsReplacer = strings.NewReplacer(
+	"・", " ",
+	"·", " ",
+)
+
+var macronsReplacer = strings.NewReplacer(
+	"ā", "a", "â", "a",
+	"ē", "e", "ê", "e",
+	"ī", "i", "î", "i",
+	"ō", "o", "ô", "o",
+	"ū", "u", "û", "u",
+)
+
+var longVowelsReplacer = strings.NewReplacer(
+	"ou", "o",
+	"oo", "o",
+	"uu", "u",
+)
+
+// normalizeTitle returns canonical form of the title for comparison only.
+// Full-width and half-width characters are unified by NFKC and macrons are collapsed.
+func normalizeTitle(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	s = titleSymbolsReplacer.Replace(s)
+	s = macronsReplacer.Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeRomajiTitle also collapses romanized long vowels, so "Tōkyō", "Toukyou" and "Tokyo"
+// are equal. It is for romaji and native titles only, English "Good" and "God" aren't the same.
+func normalizeRomajiTitle(s string) string {
+	return longVowelsReplacer.Replace(normalizeTitle(s))
+}
+
+// Title sources for display, see displayTitle.
+const (
+	titleSourceEnglish = "english"
+	titleSourceNative  = "native"
+	titleSourceRomaji  = "romaji"
+)
+
+type DisplayConfig struct {
+	TitleSource string `yaml:"title_source"`
+}
+
+func (c DisplayConfig) validate() error {
+	switch c.TitleSource {
+	case "", titleSourceEnglish, titleSourceNative, titleSourceRomaji:
+		return nil
+	default:
+		return fmt.Errorf("display.title_source: unknown source %q, known: %s, %s, %s",
+			c.TitleSource, titleSourceEnglish, titleSourceNative, titleSourceRomaji)
+	}
+}
+
+// displayTitle returns the title from the preferred source for logs and reports,
+// it falls back to other titles if the entry has no such one. Matching always uses GetTitle.
+func displayTitle(src Source, titleSource string) string {
+	var en, native, romaji string
+	switch v := src.(type) {
+	case Anime:
+		en, native, romaji = v.TitleEN, v.TitleJP, v.TitleRomaji
+	case Manga:
+		en, native, romaji = v.TitleEN, v.TitleJP, v.TitleRomaji
+	default:
+		return src.GetTitle()
+	}
+
+	var order []string
+	switch titleSource {
+	case titleSourceNative:
+		order = []string{native, en, romaji}
+	case titleSourceRomaji:
+		order = []string{romaji, en, native}
+	default:
+		return src.GetTitle()
+	}
+
+	for _, t := range order {
+		if t != "" {
+			return t
+		}
+	}
+	return ""
+}
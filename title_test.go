@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeRomajiTitle(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Tōkyō Ghoul", "Toukyou Ghoul"},
+		{"Tōkyō Ghoul", "Tokyo Ghoul"},
+		{"Toukyou Ghoul", "Tokyo Ghoul"},
+		{"Shoujo Shuumatsu Ryokou", "Shōjo Shūmatsu Ryokō"},
+		{"Re・Zero", "Re Zero"},
+		{"Re·Zero", "Re Zero"},
+		{"ＳＨＩＲＯＢＡＫＯ", "Shirobako"},
+	}
+
+	for _, tt := range tests {
+		if got, want := normalizeRomajiTitle(tt.a), normalizeRomajiTitle(tt.b); got != want {
+			t.Errorf("normalizeRomajiTitle(%q) = %q, normalizeRomajiTitle(%q) = %q, want equal", tt.a, got, tt.b, want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Re・Zero", "Re Zero", true},
+		{"ＳＨＩＲＯＢＡＫＯ", "Shirobako", true},
+		{"  Spy  x Family ", "spy x family", true},
+		{"Good", "God", false},
+		{"Book", "Bok", false},
+		{"Your Name", "Yor Name", false},
+		{"Moon", "Mon", false},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.a) == normalizeTitle(tt.b); got != tt.want {
+			t.Errorf("normalizeTitle(%q) == normalizeTitle(%q) is %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSameTypeWithTargetEnglishTitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, tgt Target
+	}{
+		{
+			name: "anime good and god",
+			src:  Anime{IDMal: 1, TitleEN: "Good", TitleJP: "グッド"},
+			tgt:  Anime{IDMal: 2, TitleEN: "God", TitleJP: "ゴッド"},
+		},
+		{
+			name: "anime your and yor",
+			src:  Anime{IDMal: 1, TitleEN: "Your Story", TitleJP: "ユア"},
+			tgt:  Anime{IDMal: 2, TitleEN: "Yor Story", TitleJP: "ヨル"},
+		},
+		{
+			name: "manga book and bok",
+			src:  Manga{IDMal: 1, IDAnilist: 1, TitleEN: "Book", TitleJP: "ブック", Chapters: 10},
+			tgt:  Manga{IDMal: 2, IDAnilist: -1, TitleEN: "Bok", TitleJP: "ボク", Chapters: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.src.(Source).SameTypeWithTarget(tt.tgt) {
+				t.Errorf("%s matches %s", tt.src, tt.tgt)
+			}
+		})
+	}
+}
+
+func TestSameTypeWithTargetRomajiTitles(t *testing.T) {
+	src := Manga{IDMal: 1, IDAnilist: 1, TitleEN: "Tokyo Ghoul", TitleJP: "Tōkyō Gūru", Chapters: 143, Volumes: 14}
+	tests := []struct {
+		name string
+		tgt  Manga
+		want bool
+	}{
+		{"same series spelled differently", Manga{IDMal: 2, IDAnilist: -1, TitleJP: "Toukyou Guuru", Chapters: 143}, true},
+		{"sequel", Manga{IDMal: 3, IDAnilist: -1, TitleEN: "Tokyo Ghoul:re", TitleJP: "Toukyou Guuru:re", Chapters: 179, Volumes: 16}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := src.SameTypeWithTarget(tt.tgt); got != tt.want {
+				t.Errorf("%s matches %s: %t, want %t", src, tt.tgt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameTitleRomajiWithMALTitle(t *testing.T) {
+	src := Anime{IDAnilist: 1, TitleRomaji: "Shōjo Shūmatsu Ryokō"}
+	tgt := Anime{IDMal: 2, TitleEN: "Shoujo Shuumatsu Ryokou"}
+	if !sameTitle(src, tgt) {
+		t.Errorf("%s doesn't match %s", src, tgt)
+	}
+}
+
+func TestDisplayTitle(t *testing.T) {
+	full := Anime{TitleEN: "Frieren: Beyond Journey's End", TitleJP: "葬送のフリーレン", TitleRomaji: "Sousou no Frieren"}
+	noEN := Manga{TitleJP: "ダンジョン飯", TitleRomaji: "Dungeon Meshi"}
+	onlyEN := Anime{TitleEN: "Scott Pilgrim Takes Off"}
+
+	tests := []struct {
+		name   string
+		src    Source
+		source string
+		want   string
+	}{
+		{"default", full, "", "Frieren: Beyond Journey's End"},
+		{"english", full, titleSourceEnglish, "Frieren: Beyond Journey's End"},
+		{"native", full, titleSourceNative, "葬送のフリーレン"},
+		{"romaji", full, titleSourceRomaji, "Sousou no Frieren"},
+		{"english fallback", noEN, titleSourceEnglish, "ダンジョン飯"},
+		{"native fallback", onlyEN, titleSourceNative, "Scott Pilgrim Takes Off"},
+		{"romaji fallback", onlyEN, titleSourceRomaji, "Scott Pilgrim Takes Off"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayTitle(tt.src, tt.source); got != tt.want {
+				t.Errorf("displayTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	u := &Updater{Prefix: "Anime", Statistics: new(Statistics), TitleSource: titleSourceRomaji}
+	u.Update(context.Background(), []Source{Anime{IDAnilist: 1, IDMal: 1, TitleEN: full.TitleEN, TitleRomaji: full.TitleRomaji, Status: StatusWatching}}, nil)
+	if len(u.Statistics.Items) != 1 || u.Statistics.Items[0].Title != "Sousou no Frieren" {
+		t.Errorf("got items %+v, want one titled in romaji", u.Statistics.Items)
+	}
+}
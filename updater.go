@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/rl404/verniy"
 )
 
 type TargetID int
@@ -13,9 +20,13 @@ type Source interface {
 	GetStatusString() string
 	GetTargetID() TargetID
 	GetTitle() string
-	GetStringDiffWithTarget(Target) string
-	SameProgressWithTarget(Target) bool
+	GetStringDiffWithTarget(Target, EntryOptions) string
+	SameProgressWithTarget(Target, EntryOptions) bool
 	SameTypeWithTarget(Target) bool
+	MatchScoreWithTarget(Target) float64
+	MergeWithTarget(Target) Source
+	WithFieldAuthority(Target, FieldAuthorityConfig) Source
+	WithoutProgressRegress(Target) (Source, bool)
 	String() string
 }
 
@@ -24,14 +35,84 @@ type Target interface {
 	String() string
 }
 
+// EntryOptions are settings of the run which change how sources are compared
+// with targets and which fields are sent to MAL.
+type EntryOptions struct {
+	OnlyStatusChanges bool // only status is compared and synced
+	ZeroScoreUnset    bool // zero score is treated as not rated and isn't synced
+	DatesNoClear      bool // missing dates don't clear MAL ones
+	DatesTerminalOnly bool // dates are synced only for completed and dropped entries
+
+	DatesLocation *time.Location // list dates are calendar dates in it, UTC if nil
+}
+
+func (o EntryOptions) location() *time.Location {
+	if o.DatesLocation == nil {
+		return time.UTC
+	}
+	return o.DatesLocation
+}
+
+// syncsScore reports whether the source score is compared and synced.
+func (o EntryOptions) syncsScore(score float64) bool {
+	return !o.ZeroScoreUnset || score != 0
+}
+
+type updatedEntry struct {
+	id  TargetID
+	src Source
+}
+
 type Updater struct {
 	Prefix       string
 	Statistics   *Statistics
 	IgnoreTitles map[string]struct{}
+	DryRun       bool   // targets aren't updated, only reported
+	OnlyTitle    string // only the source with this title is synced if set
+	OnlyNew      bool   // only sources missing in the target list are synced
+	Explain      bool   // how each source was matched or skipped is printed
+
+	SkipUnknownStatus bool
+	NoRegressProgress bool
+	IncludeHidden     bool
+	Filters           FiltersConfig
+	FieldAuthority    FieldAuthorityConfig
+	SeasonWindow      SeasonWindow
+	StrategyOrder     []string
+	NoFuzzyTitle      bool // title search is disabled, sources unmatched by ID are warned about
+	StrictIDOnly      bool // only MAL IDs are matched, other sources are reported as unmatched
+	AmbiguityMargin   float64
+	AskOnAmbiguous    bool
+	YearTolerance     int       // max difference in season years of anime matched by title, negative to disable
+	CommentsMapping   bool      // sources claimed by "anilist:<id>" token in MAL comments are synced to that entry
+	Since             time.Time // sources updated before it are skipped if set
+	PreserveScore     bool      // keep target score which is a rounding of the source one
+	ScoreFormat       verniy.ScoreFormat
+	MinEntryAge       time.Duration
+	TitleSource       string
+	NoCreateStatuses  []string // sources with these statuses update existing targets only
+	MergeDuplicates   string   // rule to pick one of duplicate sources, empty to sync all of them
+	OnInvalidProgress string   // policy for progress above the number of episodes or chapters
+	FillGapsOnly      bool     // only empty target fields are updated
+	DowngradePolicy   string   // of completed targets matched by title
+	Create            CreateConfig
+	Audit             io.Writer
+	RetryBudget       *retryBudget // shared by updaters of the run, unlimited if nil
+
+	EntryOptions
+
+	NormalizePlanProgress bool       // planned sources are synced with no progress
+	Shuffle               *rand.Rand // sources are synced in random order if set
+
+	// Events receives sync progress events if set, e.g. when the sync is embedded into another program.
+	Events chan<- SyncEvent
 
 	GetTargetByIDFunc        func(context.Context, TargetID) (Target, error)
 	GetTargetsByNameFunc     func(context.Context, string) ([]Target, error)
-	UpdateTargetBySourceFunc func(context.Context, TargetID, Source) error
+	UpdateTargetBySourceFunc func(context.Context, TargetID, Source, EntryOptions) error
+
+	updated []updatedEntry
+	trace   *explanation // of the current source, set with Explain
 }
 
 func (u *Updater) Update(ctx context.Context, srcs []Source, tgts []Target) {
@@ -40,14 +121,46 @@ func (u *Updater) Update(ctx context.Context, srcs []Source, tgts []Target) {
 		tgtsByID[tgt.GetTargetID()] = tgt
 	}
 
+	u.emit(ctx, SyncEvent{Kind: SyncEventStarted})
+	defer u.emit(ctx, SyncEvent{Kind: SyncEventFinished})
+
+	if u.OnlyTitle != "" {
+		srcs = u.filterByTitle(srcs, u.OnlyTitle)
+		if len(srcs) > 1 {
+			log.Printf("[%s] Multiple entries match title %q:", u.Prefix, u.OnlyTitle)
+			for _, src := range srcs {
+				log.Printf("[%s]   %s", u.Prefix, src.String())
+			}
+			log.Printf("[%s] Skipping sync, use a more specific title to pick one of them", u.Prefix)
+			return
+		}
+	}
+
+	if u.CommentsMapping {
+		srcs = withCommentsMapping(srcs, tgts)
+	}
+	srcs = u.mergeDuplicates(srcs)
+	if u.Shuffle != nil {
+		u.Shuffle.Shuffle(len(srcs), func(i, j int) { srcs[i], srcs[j] = srcs[j], srcs[i] })
+	}
+
 	var statusStr string
 	for _, src := range srcs {
+		if u.RetryBudget.exhausted() {
+			log.Printf("[%s] Stopping sync: %v", u.Prefix, errRetryBudgetExhausted)
+			return
+		}
+
 		if src.GetStatusString() == "" {
 			continue
 		}
 
 		u.Statistics.TotalCount++
 
+		if u.Explain {
+			u.trace = &explanation{title: src.String()}
+		}
+
 		if statusStr != src.GetStatusString() {
 			statusStr = src.GetStatusString()
 			log.Printf("[%s] Processing for status: %s", u.Prefix, statusStr)
@@ -56,25 +169,108 @@ func (u *Updater) Update(ctx context.Context, srcs []Source, tgts []Target) {
 		DPrintf("[%s] Processing for: %s", u.Prefix, src.String())
 
 		if _, ok := u.IgnoreTitles[strings.ToLower(src.GetTitle())]; ok {
-			log.Printf("[%s] Ignoring anime: %s", u.Prefix, src.GetTitle())
+			log.Printf("[%s] Ignoring anime: %s", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "ignored")
+			continue
+		}
+
+		if !u.IncludeHidden && isHidden(src) {
+			log.Printf("[%s] Skipping %s: hidden entry", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "hidden entry")
+			continue
+		}
+
+		if !u.SeasonWindow.contains(src) {
+			DPrintf("[%s] Skipping %s: outside season window", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "outside season window")
+			continue
+		}
+
+		if !u.Since.IsZero() && updatedBefore(src, u.Since) {
+			DPrintf("[%s] Skipping %s: not updated since last run", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "not updated since last run")
+			continue
+		}
+
+		if u.MinEntryAge > 0 && updatedAfter(src, timeNow().Add(-u.MinEntryAge)) {
+			log.Printf("[%s] Skipping %s: too recent, will be synced on the next run", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "too recent")
+			continue
+		}
+
+		if !u.Filters.matchesFilter(src) {
+			DPrintf("[%s] Skipping %s: filtered", u.Prefix, u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "filtered")
+			continue
+		}
+
+		if u.SkipUnknownStatus && src.GetStatusString() == string(StatusUnknown) {
+			log.Printf("[%s] Skipping %s: unknown status", u.Prefix, u.title(src))
+			u.Statistics.AddWarning("unknown status, skipped: %s", u.title(src))
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "unknown status")
+			continue
+		}
+
+		var validProgress bool
+		if src, validProgress = u.checkProgress(src); !validProgress {
 			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "invalid progress")
 			continue
 		}
+		src = u.normalizePlanProgress(src)
 
 		u.updateSourceByTargets(ctx, src, tgtsByID)
 	}
 }
 
+func (u *Updater) filterByTitle(srcs []Source, title string) []Source {
+	var res []Source
+	for _, src := range srcs {
+		if matchesTitle(src, title) {
+			res = append(res, src)
+		}
+	}
+
+	if len(res) == 0 {
+		log.Printf("[%s] No entries match title %q", u.Prefix, title)
+	}
+
+	return res
+}
+
 func (u *Updater) updateSourceByTargets(ctx context.Context, src Source, tgts map[TargetID]Target) {
 	tgtID := src.GetTargetID()
 
+	var diff string
 	if !(*forceSync) { // filter sources by different progress with targets
 		tgt, ok := tgts[src.GetTargetID()]
-		if !ok {
+		if ok {
+			u.trace.addf("found in MAL list by id %d", src.GetTargetID())
+		} else {
 			var err error
 			tgt, err = u.findTarget(ctx, src)
+			if errors.Is(err, errMalNotAccessible) {
+				u.skipNotAccessible(ctx, src)
+				return
+			}
+			if errors.Is(err, errTargetNotFound) && u.StrictIDOnly {
+				u.Statistics.AddWarning("unmatched, only ID matching is enabled: %s (AniList ID %d)", u.title(src), sourceAnilistID(src))
+			} else if errors.Is(err, errTargetNotFound) && u.NoFuzzyTitle {
+				u.Statistics.AddWarning("unmatched, title matching is disabled: %s", u.title(src))
+			}
 			if err != nil {
 				log.Printf("[%s] Error processing target anime: %v", u.Prefix, err)
+				if !errors.Is(err, errTargetNotFound) {
+					u.Statistics.AddError(err)
+				}
+				u.emit(ctx, SyncEvent{Kind: SyncEventError, Title: u.title(src), SourceID: sourceAnilistID(src), Err: err})
 				u.Statistics.SkippedCount++
 				return
 			}
@@ -82,68 +278,352 @@ func (u *Updater) updateSourceByTargets(ctx context.Context, src Source, tgts ma
 
 		DPrintf("[%s] Target: %s", u.Prefix, tgt.String())
 
-		if src.SameProgressWithTarget(tgt) {
+		tgtID = tgt.GetTargetID()
+		u.emit(ctx, SyncEvent{Kind: SyncEventMatched, Title: u.title(src), TargetID: tgtID})
+		if _, exists := tgts[tgtID]; exists && u.OnlyNew {
+			u.skipExisting(ctx, src)
+			return
+		}
+
+		src = src.MergeWithTarget(tgt)
+		src = src.WithFieldAuthority(tgt, u.FieldAuthority)
+		if u.PreserveScore {
+			src = withPreservedScore(src, tgt, u.ScoreFormat)
+		}
+		if u.FillGapsOnly {
+			src = withGapsOnly(src, tgt)
+		}
+
+		if !u.allowDowngrade(src, tgt) {
 			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, "title match downgrade")
 			return
 		}
 
-		log.Printf("[%s] Title: %s", u.Prefix, src.GetTitle())
-		log.Printf("[%s] Progress is not same, need to update: %s", u.Prefix, src.GetStringDiffWithTarget(tgt))
+		if u.NoRegressProgress {
+			var regressed bool
+			if src, regressed = src.WithoutProgressRegress(tgt); regressed {
+				u.Statistics.AddWarning("target ahead, not regressed: %s", u.title(src))
+			}
+		}
 
-		tgtID = tgt.GetTargetID()
+		if u.sameWithTarget(src, tgt) {
+			reason := "no changes"
+			if u.ignoresNonStatusChanges(src, tgt) {
+				reason = "non-status change ignored"
+				DPrintf("[%s] Skipping %s: %s", u.Prefix, u.title(src), reason)
+			}
+			u.Statistics.SkippedCount++
+			u.emitSkipped(ctx, src, reason)
+			return
+		}
+
+		diff = src.GetStringDiffWithTarget(tgt, u.EntryOptions)
+
+		log.Printf("[%s] Title: %s", u.Prefix, u.title(src))
+		log.Printf("[%s] Progress is not same, need to update: %s", u.Prefix, diff)
+	} else if _, exists := tgts[tgtID]; exists && u.OnlyNew {
+		u.skipExisting(ctx, src)
+		return
+	}
+
+	if _, exists := tgts[tgtID]; !exists && containsFold(u.NoCreateStatuses, src.GetStatusString()) {
+		DPrintf("[%s] Skipping %s: not in MAL list, %s entries are not created", u.Prefix, u.title(src), src.GetStatusString())
+		u.Statistics.SkippedCount++
+		u.emitSkipped(ctx, src, "not created with status "+src.GetStatusString())
+		return
 	}
 
-	if *dryRun { // skip update if dry run
-		log.Printf("[%s] Dry run: Skipping update for anime %s", u.Prefix, src.GetTitle())
+	if _, exists := tgts[tgtID]; !exists && u.Create.belowThreshold(src) {
+		DPrintf("[%s] Skipping %s: not in MAL list, below create threshold", u.Prefix, u.title(src))
+		u.Statistics.SkippedCount++
+		u.emitSkipped(ctx, src, "below create threshold")
 		return
 	}
 
-	u.updateTarget(ctx, tgtID, src)
+	if u.DryRun { // skip update if dry run
+		log.Printf("[%s] Dry run: Skipping update for anime %s", u.Prefix, u.title(src))
+		u.emit(ctx, SyncEvent{Kind: SyncEventSkipped, Title: u.title(src), SourceID: sourceAnilistID(src), TargetID: tgtID, Reason: reasonDryRun, Diff: diff})
+		return
+	}
+
+	u.updateTarget(ctx, tgtID, src, diff)
 }
 
-func (u *Updater) findTarget(ctx context.Context, src Source) (Target, error) {
-	tgtID := src.GetTargetID()
+const (
+	StrategyID    = "id"
+	StrategyTitle = "title"
+)
 
-	if tgtID > 0 {
-		DPrintf("[%s] Finding target by id: %d", u.Prefix, tgtID)
+var defaultStrategyOrder = []string{StrategyID, StrategyTitle}
 
-		tgt, err := u.GetTargetByIDFunc(ctx, tgtID)
+var errTargetNotFound = errors.New("no target found")
+
+func (u *Updater) findTarget(ctx context.Context, src Source) (Target, error) {
+	for _, strategy := range u.StrategyOrder {
+		var (
+			tgt Target
+			err error
+		)
+
+		u.trace.addf("strategy %s", strategy)
+
+		switch strategy {
+		case StrategyID:
+			tgt, err = u.findTargetByID(ctx, src)
+		case StrategyTitle:
+			tgt, err = u.findTargetByName(ctx, src)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error getting mal anime by id: %s: %w", src.GetTitle(), err)
+			return nil, err
+		}
+		if tgt != nil {
+			return tgt, nil
 		}
-		return tgt, nil
 	}
 
-	DPrintf("[%s] Finding target by name: %s", u.Prefix, src.GetTitle())
+	u.trace.addf("all strategies failed: %v", u.StrategyOrder)
 
-	tgts, err := u.GetTargetsByNameFunc(ctx, src.GetTitle())
+	return nil, fmt.Errorf("%w for source: %s", errTargetNotFound, u.title(src))
+}
+
+func (u *Updater) findTargetByID(ctx context.Context, src Source) (Target, error) {
+	tgtID := src.GetTargetID()
+	if tgtID <= 0 {
+		u.trace.addf("  no MAL id on AniList")
+		return nil, nil
+	}
+
+	DPrintf("[%s] Finding target by id: %d", u.Prefix, tgtID)
+
+	tgt, err := u.GetTargetByIDFunc(ctx, tgtID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting targets by source name: %s: %w", src.GetTitle(), err)
+		return nil, fmt.Errorf("error getting mal anime by id: %s: %w", u.title(src), err)
 	}
+	if tgt != nil {
+		u.trace.addf("  found by MAL id %d: %s", tgtID, tgt.String())
+	}
+	return tgt, nil
+}
+
+func (u *Updater) findTargetByName(ctx context.Context, src Source) (Target, error) {
+	DPrintf("[%s] Finding target by name: %s", u.Prefix, u.title(src))
 
+	tgts, err := u.searchTargets(ctx, src.GetTitle())
+	if err != nil {
+		return nil, fmt.Errorf("error getting targets by source name: %s: %w", u.title(src), err)
+	}
+
+	u.trace.addf("  %d search results", len(tgts))
+
+	var candidates []Target
 	for _, tgt := range tgts {
-		if src.SameTypeWithTarget(tgt) {
-			DPrintf("[%s] Found target by name: %s", u.Prefix, src.GetTitle())
-			return tgt, nil
+		if seasonYearsClose(src, tgt, u.YearTolerance) && src.SameTypeWithTarget(tgt) {
+			DPrintf("[%s] Found target by name: %s", u.Prefix, u.title(src))
+			u.trace.addf("  accepted: %s", tgt.String())
+			candidates = append(candidates, tgt)
 		} else {
 			DPrintf("[%s] Ignoring target by name: %s", u.Prefix, tgt.String())
+			u.trace.addf("  rejected: %s", tgt.String())
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ranked, ambiguous := u.rankCandidates(src, candidates)
+	if u.AskOnAmbiguous && (ambiguous || !sameTitle(src, ranked[0])) {
+		return u.confirmCandidate(src, ranked), nil
+	}
+	if ambiguous {
+		log.Printf("[%s] Ambiguous title match for %s: %s and %s", u.Prefix, u.title(src), ranked[0].String(), ranked[1].String())
+		u.Statistics.AddWarning("ambiguous title match, skipped: %s (MAL ids %d and %d)", u.title(src), ranked[0].GetTargetID(), ranked[1].GetTargetID())
+		return nil, nil
+	}
+
+	return ranked[0], nil
+}
+
+// timeNow is the clock of sync.min_entry_age, replaced in tests.
+var timeNow = time.Now
+
+// searchRetryDelays are waits before repeated title searches failed by transient errors.
+var searchRetryDelays = []time.Duration{time.Second, 5 * time.Second}
+
+// searchTargets searches targets by name. Transient errors are retried, so they aren't
+// reported as unmatched entries. Searches rejected as not found or invalid (e.g. too
+// short title) have no results rather than fail.
+func (u *Updater) searchTargets(ctx context.Context, name string) ([]Target, error) {
+	for attempt := 0; ; attempt++ {
+		tgts, err := u.GetTargetsByNameFunc(ctx, name)
+		if err == nil {
+			return tgts, nil
+		}
+
+		category := classifyError(err)
+		if category == ErrorCategoryNotFound || category == ErrorCategoryValidation {
+			DPrintf("[%s] No search results for %s: %v", u.Prefix, name, err)
+			return nil, nil
+		}
+		if !category.isTransient() || attempt == len(searchRetryDelays) {
+			return nil, err
+		}
+		if !u.RetryBudget.take() {
+			return nil, fmt.Errorf("%w: %w", errRetryBudgetExhausted, err)
+		}
+
+		log.Printf("[%s] Search for %s failed, retrying: %v", u.Prefix, name, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(searchRetryDelays[attempt]):
+		}
+	}
+}
+
+// rankCandidates sorts candidates by match score, search order breaks ties.
+// It reports whether the second best is within AmbiguityMargin from the best.
+func (u *Updater) rankCandidates(src Source, candidates []Target) ([]Target, bool) {
+	if len(candidates) == 1 {
+		return candidates, false
+	}
+
+	scores := make(map[TargetID]float64, len(candidates))
+	for _, tgt := range candidates {
+		scores[tgt.GetTargetID()] = src.MatchScoreWithTarget(tgt)
+		u.trace.addf("  score %.2f: %s", scores[tgt.GetTargetID()], tgt.String())
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].GetTargetID()] > scores[candidates[j].GetTargetID()]
+	})
+
+	ambiguous := scores[candidates[0].GetTargetID()]-scores[candidates[1].GetTargetID()] < u.AmbiguityMargin
+	if ambiguous {
+		u.trace.addf("  ambiguous, margin %.2f", u.AmbiguityMargin)
+	}
+	return candidates, ambiguous
+}
+
+// maxAskedCandidates limits questions about a single source.
+const maxAskedCandidates = 3
+
+// confirmCandidate asks the user to confirm the uncertain match, without terminal
+// the source is recorded for manual review instead. It returns nil if nothing is confirmed.
+func (u *Updater) confirmCandidate(src Source, ranked []Target) Target {
+	if !stdinIsTerminal() {
+		u.Statistics.AddWarning("uncertain title match, review it manually: %s (best MAL id %d)", u.title(src), ranked[0].GetTargetID())
+		u.trace.addf("  uncertain, no terminal to ask")
+		return nil
+	}
+
+	for i, tgt := range ranked {
+		if i == maxAskedCandidates {
+			break
+		}
+		if askYesNo(fmt.Sprintf("[%s] Match %s to MAL %s?", u.Prefix, src.String(), tgt.String())) {
+			log.Printf("[%s] Match confirmed, add mal:%d to AniList notes and enable matching.notes_mapping to skip the question next time", u.Prefix, tgt.GetTargetID())
+			u.trace.addf("  confirmed by user: %s", tgt.String())
+			return tgt
 		}
 	}
 
-	return nil, fmt.Errorf("no target found for source: %s", src.GetTitle())
+	u.trace.addf("  not confirmed by user")
+	return nil
 }
 
-func (u *Updater) updateTarget(ctx context.Context, id TargetID, src Source) {
-	DPrintf("[%s] Updating %s", u.Prefix, src.GetTitle())
+func (u *Updater) updateTarget(ctx context.Context, id TargetID, src Source, diff string) {
+	DPrintf("[%s] Updating %s", u.Prefix, u.title(src))
 
-	if err := u.UpdateTargetBySourceFunc(ctx, id, src); err != nil {
-		log.Printf("[%s] Error updating target: %s: %v", u.Prefix, src.GetTitle(), err)
+	err := u.UpdateTargetBySourceFunc(ctx, id, src, u.EntryOptions)
+	if errors.Is(err, errMalNotAccessible) {
+		u.skipNotAccessible(ctx, src)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] Error updating target: %s: %v", u.Prefix, u.title(src), err)
+		u.Statistics.AddError(err)
+		u.emit(ctx, SyncEvent{Kind: SyncEventError, Title: u.title(src), SourceID: sourceAnilistID(src), TargetID: id, Err: err})
 		return
 	}
 
-	log.Printf("[%s] Updated %s", u.Prefix, src.GetTitle())
+	log.Printf("[%s] Updated %s", u.Prefix, u.title(src))
 
 	u.Statistics.UpdatedCount++
+	u.emit(ctx, SyncEvent{Kind: SyncEventUpdated, Title: u.title(src), SourceID: sourceAnilistID(src), TargetID: id, Diff: diff})
+	u.updated = append(u.updated, updatedEntry{id: id, src: src})
+
+	if u.Audit != nil {
+		err := writeAuditRecord(u.Audit, auditRecord{
+			Time:      time.Now(),
+			Direction: directionAnilistToMal,
+			Type:      strings.ToLower(u.Prefix),
+			Title:     u.title(src),
+			IDAnilist: sourceAnilistID(src),
+			IDMal:     int(id),
+			Diff:      diff,
+		})
+		if err != nil {
+			log.Printf("[%s] Error writing audit record: %s: %v", u.Prefix, u.title(src), err)
+		}
+	}
+}
+
+// Verify re-fetches targets updated by the last Update call and records
+// a warning for every target which doesn't match its source.
+func (u *Updater) Verify(ctx context.Context) {
+	log.Printf("[%s] Verifying %d updated entries...", u.Prefix, len(u.updated))
+
+	for _, e := range u.updated {
+		tgt, err := u.GetTargetByIDFunc(ctx, e.id)
+		if err != nil {
+			u.Statistics.AddWarning("verify: error getting target %s: %v", u.title(e.src), err)
+			continue
+		}
+
+		if !u.sameWithTarget(e.src, tgt) {
+			u.Statistics.AddWarning("verify: update was not applied for %s: %s", u.title(e.src), e.src.GetStringDiffWithTarget(tgt, u.EntryOptions))
+		}
+	}
+}
+
+// sameWithTarget reports whether the target has nothing to update from the source.
+func (u *Updater) sameWithTarget(src Source, tgt Target) bool {
+	if u.OnlyStatusChanges {
+		t, ok := tgt.(interface{ GetStatusString() string })
+		return ok && t.GetStatusString() == src.GetStatusString()
+	}
+	return src.SameProgressWithTarget(tgt, u.EntryOptions)
+}
+
+// ignoresNonStatusChanges reports whether the source differs from the target
+// only in fields which aren't synced with OnlyStatusChanges.
+func (u *Updater) ignoresNonStatusChanges(src Source, tgt Target) bool {
+	if !u.OnlyStatusChanges {
+		return false
+	}
+	opts := u.EntryOptions
+	opts.OnlyStatusChanges = false
+	return !src.SameProgressWithTarget(tgt, opts)
+}
+
+func (u *Updater) skipExisting(ctx context.Context, src Source) {
+	DPrintf("[%s] Skipping %s: exists, only-new", u.Prefix, u.title(src))
+	u.Statistics.SkippedCount++
+	u.emitSkipped(ctx, src, "exists, only-new")
+}
+
+func (u *Updater) skipNotAccessible(ctx context.Context, src Source) {
+	log.Printf("[%s] Skipping %s: %s", u.Prefix, u.title(src), errMalNotAccessible)
+	u.Statistics.AddWarning("%s (R18, region-locked or delisted): %s", errMalNotAccessible, u.title(src))
+	u.Statistics.SkippedCount++
+	u.emitSkipped(ctx, src, errMalNotAccessible.Error())
+}
+
+// title returns the title of the source for logs and reports.
+func (u *Updater) title(src Source) string {
+	return displayTitle(src, u.TitleSource)
 }
 
 func DPrintf(format string, v ...any) {
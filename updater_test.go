@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdaterSameWithTargetOnlyStatusChanges(t *testing.T) {
+	src := Anime{IDMal: 1, Status: StatusWatching, Progress: 5, Score: 8}
+	tests := []struct {
+		name              string
+		tgt               Anime
+		onlyStatusChanges bool
+		want              bool
+	}{
+		{"progress differs", Anime{IDMal: 1, Status: StatusWatching, Progress: 3, Score: 8}, false, false},
+		{"progress differs, only status", Anime{IDMal: 1, Status: StatusWatching, Progress: 3, Score: 8}, true, true},
+		{"status differs, only status", Anime{IDMal: 1, Status: StatusCompleted, Progress: 5, Score: 8}, true, false},
+		{"same", Anime{IDMal: 1, Status: StatusWatching, Progress: 5, Score: 8}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Updater{EntryOptions: EntryOptions{OnlyStatusChanges: tt.onlyStatusChanges}}
+			if got := u.sameWithTarget(src, tt.tgt); got != tt.want {
+				t.Errorf("sameWithTarget() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnlyStatusChangesSkipReason(t *testing.T) {
+	tests := []struct {
+		name string
+		tgt  Anime
+		want string
+	}{
+		{"progress differs", Anime{IDMal: 1, Status: StatusWatching, Progress: 3, Score: 8}, "non-status change ignored"},
+		{"same", Anime{IDMal: 1, Status: StatusWatching, Progress: 5, Score: 8}, "no changes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Updater{
+				Prefix:       "Anime",
+				Statistics:   new(Statistics),
+				EntryOptions: EntryOptions{OnlyStatusChanges: true},
+			}
+			u.Update(context.Background(), []Source{
+				Anime{IDAnilist: 1, IDMal: 1, Status: StatusWatching, Progress: 5, Score: 8},
+			}, []Target{tt.tgt})
+
+			if len(u.Statistics.Items) != 1 || u.Statistics.Items[0].Reason != tt.want {
+				t.Errorf("got items %+v, want one skipped with reason %q", u.Statistics.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUpdateOptionsOnlyStatusChanges(t *testing.T) {
+	a := Anime{Status: StatusWatching, Progress: 5, Score: 8}
+	if got := len(a.GetUpdateOptions(EntryOptions{OnlyStatusChanges: true})); got != 1 {
+		t.Errorf("anime: got %d options, want status only", got)
+	}
+
+	m := Manga{Status: MangaStatusReading, Progress: 5, Score: 8}
+	if got := len(m.GetUpdateOptions(EntryOptions{OnlyStatusChanges: true})); got != 1 {
+		t.Errorf("manga: got %d options, want status only", got)
+	}
+}
+
+func TestEntryOptionsZeroScoreUnset(t *testing.T) {
+	src := Anime{IDMal: 1, Status: StatusWatching, Progress: 5}
+	tgt := Anime{IDMal: 1, Status: StatusWatching, Progress: 5, Score: 7}
+
+	if src.SameProgressWithTarget(tgt, EntryOptions{}) {
+		t.Error("zero score is the same as 7")
+	}
+	o := EntryOptions{ZeroScoreUnset: true}
+	if !src.SameProgressWithTarget(tgt, o) {
+		t.Error("unset score differs from 7")
+	}
+	if got, want := len(src.GetUpdateOptions(o)), len(src.GetUpdateOptions(EntryOptions{}))-1; got != want {
+		t.Errorf("got %d options, want %d without score", got, want)
+	}
+
+	m := Manga{IDMal: 1, Status: MangaStatusReading, Progress: 5}
+	mt := Manga{IDMal: 1, Status: MangaStatusReading, Progress: 5, Score: 7}
+	if !m.SameProgressWithTarget(mt, o) {
+		t.Error("manga: unset score differs from 7")
+	}
+}
+
+func TestEntryOptionsDatesNoClear(t *testing.T) {
+	src := Anime{IDMal: 1, Status: StatusCompleted, Progress: 12, Score: 8}
+	tgt := src
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tgt.StartedAt = &started
+
+	if diff := src.GetStringDiffWithTarget(tgt, EntryOptions{}); !strings.Contains(diff, "StartedAt") {
+		t.Errorf("diff %s has no start date", diff)
+	}
+	o := EntryOptions{DatesNoClear: true}
+	if diff := src.GetStringDiffWithTarget(tgt, o); strings.Contains(diff, "StartedAt") {
+		t.Errorf("diff %s clears start date", diff)
+	}
+	if got, want := len(src.GetUpdateOptions(o)), len(src.GetUpdateOptions(EntryOptions{}))-2; got != want {
+		t.Errorf("got %d options, want %d without dates", got, want)
+	}
+}
+
+func TestEntryOptionsDatesTerminalOnly(t *testing.T) {
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	o := EntryOptions{DatesTerminalOnly: true}
+
+	watching := Manga{IDMal: 1, Status: MangaStatusReading, Progress: 5, StartedAt: &started}
+	if diff := watching.GetStringDiffWithTarget(Manga{IDMal: 1, Status: MangaStatusReading, Progress: 5}, o); strings.Contains(diff, "StartedAt") {
+		t.Errorf("reading manga dates are synced: %s", diff)
+	}
+
+	completed := Manga{IDMal: 1, Status: MangaStatusCompleted, Progress: 5, StartedAt: &started}
+	if diff := completed.GetStringDiffWithTarget(Manga{IDMal: 1, Status: MangaStatusCompleted, Progress: 5}, o); !strings.Contains(diff, "StartedAt") {
+		t.Errorf("completed manga dates aren't synced: %s", diff)
+	}
+}
+
+func TestSearchTargets(t *testing.T) {
+	delays := searchRetryDelays
+	searchRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	t.Cleanup(func() { searchRetryDelays = delays })
+
+	frieren := Anime{IDMal: 52991, TitleEN: "Frieren"}
+	errTimeout := fmt.Errorf("search: %w", timeoutError{})
+	tests := []struct {
+		name      string
+		errs      []error // of the calls before the last one, which returns results
+		results   []Target
+		wantCalls int
+		wantErr   bool
+		wantTgts  int
+	}{
+		{"no results", nil, nil, 1, false, 0},
+		{"results", nil, []Target{frieren}, 1, false, 1},
+		{"not found", []error{malError(http.StatusNotFound)}, nil, 1, false, 0},
+		{"too short title", []error{malError(http.StatusBadRequest)}, nil, 1, false, 0},
+		{"transient error retried", []error{malError(http.StatusBadGateway), errTimeout}, []Target{frieren}, 3, false, 1},
+		{"transient error persists", []error{errTimeout, errTimeout, errTimeout}, nil, 3, true, 0},
+		{"auth error isn't retried", []error{malError(http.StatusUnauthorized)}, nil, 1, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			u := &Updater{
+				Prefix: "Anime",
+				GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+					calls++
+					if calls <= len(tt.errs) {
+						return nil, tt.errs[calls-1]
+					}
+					return tt.results, nil
+				},
+			}
+
+			tgts, err := u.searchTargets(context.Background(), "Frieren")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if len(tgts) != tt.wantTgts {
+				t.Errorf("got %d targets, want %d", len(tgts), tt.wantTgts)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestUpdaterShuffleWithSeed(t *testing.T) {
+	var srcs []Source
+	var tgts []Target
+	for id := 1; id <= 10; id++ {
+		srcs = append(srcs, Anime{IDAnilist: id, IDMal: id, Status: StatusWatching, Progress: 2})
+		tgts = append(tgts, Anime{IDAnilist: -1, IDMal: id, Status: StatusWatching, Progress: 1})
+	}
+
+	order := func(shuffle *rand.Rand) []TargetID {
+		var ids []TargetID
+		u := &Updater{
+			Prefix:     "Anime",
+			Statistics: new(Statistics),
+			Shuffle:    shuffle,
+			UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+				ids = append(ids, id)
+				return nil
+			},
+		}
+		u.Update(context.Background(), append([]Source(nil), srcs...), tgts)
+		return ids
+	}
+
+	sorted := fmt.Sprint(order(nil))
+	if sorted != "[1 2 3 4 5 6 7 8 9 10]" {
+		t.Fatalf("got order %s without shuffle", sorted)
+	}
+
+	first, second := fmt.Sprint(order(rand.New(rand.NewSource(42)))), fmt.Sprint(order(rand.New(rand.NewSource(42))))
+	if first == sorted {
+		t.Errorf("order %s isn't shuffled", first)
+	}
+	if first != second {
+		t.Errorf("the same seed gave orders %s and %s", first, second)
+	}
+}
+
+func TestUpdaterNoFuzzyTitleWarnsUnmatched(t *testing.T) {
+	var searched bool
+	u := &Updater{
+		Prefix:        "Anime",
+		Statistics:    new(Statistics),
+		StrategyOrder: []string{StrategyID},
+		NoFuzzyTitle:  true,
+		GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+			searched = true
+			return nil, nil
+		},
+	}
+	u.Update(context.Background(), []Source{Anime{IDAnilist: 1, TitleEN: "Frieren", Status: StatusWatching}}, nil)
+
+	if searched {
+		t.Error("title search is used")
+	}
+	if len(u.Statistics.Warnings) != 1 || !strings.Contains(u.Statistics.Warnings[0], "title matching is disabled") {
+		t.Errorf("got warnings %q, want unmatched one", u.Statistics.Warnings)
+	}
+}
+
+func TestUpdaterOnlyTitle(t *testing.T) {
+	srcs := []Source{
+		Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching, Progress: 2},
+		Anime{IDAnilist: 2, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching, Progress: 2},
+		Anime{IDAnilist: 3, IDMal: 3, TitleEN: "Dandadan Season 2", Status: StatusWatching, Progress: 2},
+	}
+	var tgts []Target
+	for id := 1; id <= 3; id++ {
+		tgts = append(tgts, Anime{IDAnilist: -1, IDMal: id, Status: StatusWatching, Progress: 1})
+	}
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"frieren", "[1]"},
+		{"Dandadan Season 2", "[3]"},
+		{"Unknown", "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			updated := []TargetID{}
+			u := &Updater{
+				Prefix:     "Anime",
+				Statistics: new(Statistics),
+				OnlyTitle:  tt.title,
+				UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+					updated = append(updated, id)
+					return nil
+				},
+			}
+			u.Update(context.Background(), srcs, tgts)
+
+			if got := fmt.Sprint(updated); got != tt.want {
+				t.Errorf("got updates %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdaterNoRegressProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		src, tgt  int
+		wantSent  string
+		wantWarns int
+	}{
+		{"12 to 13", 12, 13, "[]", 1},
+		{"13 to 12", 13, 12, "[13]", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := []int{}
+			u := &Updater{
+				Prefix:            "Anime",
+				Statistics:        new(Statistics),
+				NoRegressProgress: true,
+				UpdateTargetBySourceFunc: func(_ context.Context, _ TargetID, src Source, _ EntryOptions) error {
+					sent = append(sent, src.(Anime).Progress)
+					return nil
+				},
+			}
+			u.Update(context.Background(),
+				[]Source{Anime{IDAnilist: 1, IDMal: 1, Status: StatusWatching, Progress: tt.src, NumEpisodes: 24}},
+				[]Target{Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching, Progress: tt.tgt, NumEpisodes: 24}},
+			)
+
+			if got := fmt.Sprint(sent); got != tt.wantSent {
+				t.Errorf("got sent progress %s, want %s", got, tt.wantSent)
+			}
+			if len(u.Statistics.Warnings) != tt.wantWarns {
+				t.Errorf("got warnings %q, want %d", u.Statistics.Warnings, tt.wantWarns)
+			}
+		})
+	}
+}
+
+func TestUpdaterOnlyNew(t *testing.T) {
+	updated := []TargetID{}
+	u := &Updater{
+		Prefix:        "Anime",
+		Statistics:    new(Statistics),
+		OnlyNew:       true,
+		StrategyOrder: []string{StrategyID},
+		GetTargetByIDFunc: func(_ context.Context, id TargetID) (Target, error) {
+			return Anime{IDAnilist: -1, IDMal: int(id), NumEpisodes: 12}, nil
+		},
+		UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+			updated = append(updated, id)
+			return nil
+		},
+	}
+	u.Update(context.Background(), []Source{
+		Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusWatching, Progress: 5, NumEpisodes: 12},
+		Anime{IDAnilist: 2, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching, Progress: 5, NumEpisodes: 12},
+	}, []Target{
+		Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching, Progress: 3, NumEpisodes: 12},
+	})
+
+	if got := fmt.Sprint(updated); got != "[2]" {
+		t.Errorf("got updates %s, want only the entry missing in MAL list", got)
+	}
+	if len(u.Statistics.Items) != 2 || u.Statistics.Items[0].Reason != "exists, only-new" {
+		t.Errorf("got items %+v, want the existing entry skipped as exists, only-new", u.Statistics.Items)
+	}
+}
+
+func TestUpdaterMinEntryAge(t *testing.T) {
+	now := time.Date(2024, 3, 16, 12, 0, 0, 0, time.UTC)
+	oldNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldNow })
+
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	tests := []struct {
+		name      string
+		updatedAt *time.Time
+		want      string
+	}{
+		{"edited seconds ago", at(-30 * time.Second), "too recent"},
+		{"edited minutes ago", at(-5 * time.Minute), ""},
+		{"unknown update time", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated bool
+			u := &Updater{
+				Prefix:      "Anime",
+				Statistics:  new(Statistics),
+				MinEntryAge: 2 * time.Minute,
+				UpdateTargetBySourceFunc: func(context.Context, TargetID, Source, EntryOptions) error {
+					updated = true
+					return nil
+				},
+			}
+			u.Update(context.Background(),
+				[]Source{Anime{IDAnilist: 1, IDMal: 1, Status: StatusWatching, Progress: 5, UpdatedAt: tt.updatedAt}},
+				[]Target{Anime{IDAnilist: -1, IDMal: 1, Status: StatusWatching, Progress: 3}},
+			)
+
+			if updated != (tt.want == "") {
+				t.Errorf("got updated %t, want %t", updated, tt.want == "")
+			}
+			if tt.want != "" && (len(u.Statistics.Items) != 1 || u.Statistics.Items[0].Reason != tt.want) {
+				t.Errorf("got items %+v, want one skipped with reason %q", u.Statistics.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdaterNoCreateStatuses(t *testing.T) {
+	updated := []TargetID{}
+	u := &Updater{
+		Prefix:           "Anime",
+		Statistics:       new(Statistics),
+		StrategyOrder:    []string{StrategyID},
+		NoCreateStatuses: []string{"Plan_To_Watch"},
+		GetTargetByIDFunc: func(_ context.Context, id TargetID) (Target, error) {
+			return Anime{IDAnilist: -1, IDMal: int(id), NumEpisodes: 12}, nil
+		},
+		UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+			updated = append(updated, id)
+			return nil
+		},
+	}
+	u.Update(context.Background(), []Source{
+		// Resolvable by ID, but not in MAL list.
+		Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusPlanToWatch, NumEpisodes: 12},
+		Anime{IDAnilist: 2, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching, Progress: 2, NumEpisodes: 12},
+		// In MAL list.
+		Anime{IDAnilist: 3, IDMal: 3, TitleEN: "Bocchi the Rock!", Status: StatusPlanToWatch, NumEpisodes: 12},
+	}, []Target{
+		Anime{IDAnilist: -1, IDMal: 3, Status: StatusWatching, Progress: 1, NumEpisodes: 12},
+	})
+
+	if got := fmt.Sprint(updated); got != "[2 3]" {
+		t.Errorf("got updates %s, want [2 3]", got)
+	}
+	var reasons []string
+	for _, item := range u.Statistics.Items {
+		if item.Action == "skipped" {
+			reasons = append(reasons, item.Reason)
+		}
+	}
+	if fmt.Sprint(reasons) != "[not created with status plan_to_watch]" {
+		t.Errorf("got skip reasons %q, want only the absent planned entry skipped", reasons)
+	}
+}
+
+func TestUpdaterStrictIDOnlyWarnsUnmatched(t *testing.T) {
+	order, err := strategyOrder(MatchingConfig{StrategyOrder: []string{StrategyID, StrategyTitle}}, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var searched, updated bool
+	u := &Updater{
+		Prefix:        "Anime",
+		Statistics:    new(Statistics),
+		StrategyOrder: order,
+		StrictIDOnly:  true,
+		// The entry has no MAL ID, but the title search would find it.
+		GetTargetsByNameFunc: func(context.Context, string) ([]Target, error) {
+			searched = true
+			return []Target{Anime{IDAnilist: -1, IDMal: 52991, TitleEN: "Frieren"}}, nil
+		},
+		UpdateTargetBySourceFunc: func(context.Context, TargetID, Source, EntryOptions) error {
+			updated = true
+			return nil
+		},
+	}
+	u.Update(context.Background(), []Source{Anime{IDAnilist: 154587, TitleEN: "Frieren", Status: StatusWatching}}, nil)
+
+	if searched || updated {
+		t.Errorf("got searched %t and updated %t, want the entry unmatched", searched, updated)
+	}
+	if len(u.Statistics.Warnings) != 1 || !strings.Contains(u.Statistics.Warnings[0], "unmatched, only ID matching is enabled: Frieren (AniList ID 154587)") {
+		t.Errorf("got warnings %q, want unmatched one", u.Statistics.Warnings)
+	}
+}
+
+func TestUpdaterCreateThresholds(t *testing.T) {
+	updated := []TargetID{}
+	u := &Updater{
+		Prefix:        "Anime",
+		Statistics:    new(Statistics),
+		StrategyOrder: []string{StrategyID},
+		Create:        CreateConfig{MinProgress: 1, MinScore: 7},
+		GetTargetByIDFunc: func(_ context.Context, id TargetID) (Target, error) {
+			return Anime{IDAnilist: -1, IDMal: int(id), NumEpisodes: 12}, nil
+		},
+		UpdateTargetBySourceFunc: func(_ context.Context, id TargetID, _ Source, _ EntryOptions) error {
+			updated = append(updated, id)
+			return nil
+		},
+	}
+	u.Update(context.Background(), []Source{
+		// Not in MAL list.
+		Anime{IDAnilist: 1, IDMal: 1, TitleEN: "Frieren", Status: StatusPlanToWatch, NumEpisodes: 12},
+		Anime{IDAnilist: 2, IDMal: 2, TitleEN: "Dandadan", Status: StatusWatching, Progress: 3, NumEpisodes: 12},
+		Anime{IDAnilist: 3, IDMal: 3, TitleEN: "Bocchi the Rock!", Status: StatusPlanToWatch, Score: 8, NumEpisodes: 12},
+		// In MAL list, it is updated even below the thresholds.
+		Anime{IDAnilist: 4, IDMal: 4, TitleEN: "Kaguya-sama", Status: StatusPlanToWatch, NumEpisodes: 12},
+	}, []Target{
+		Anime{IDAnilist: -1, IDMal: 4, Status: StatusWatching, Progress: 1, NumEpisodes: 12},
+	})
+
+	if got := fmt.Sprint(updated); got != "[2 3 4]" {
+		t.Errorf("got updates %s, want [2 3 4]", got)
+	}
+	var skipped []string
+	for _, item := range u.Statistics.Items {
+		if item.Action == "skipped" {
+			skipped = append(skipped, fmt.Sprintf("%d: %s", item.SourceID, item.Reason))
+		}
+	}
+	if fmt.Sprint(skipped) != "[1: below create threshold]" {
+		t.Errorf("got skipped %q, want only the untouched entry missing in MAL list", skipped)
+	}
+}